@@ -0,0 +1,68 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include "webp.h"
+*/
+import "C"
+import (
+	"image/color"
+	"unsafe"
+)
+
+// HasTransparency report whether the picture has any pixel which is not fully opaque
+func (p *Picture) HasTransparency() bool {
+	return p.pic != nil && int(C.WebPPictureHasTransparency(p.pic)) != 0
+}
+
+// CleanupTransparentArea replace the colors under fully transparent areas by flat
+// values to help the lossy compression, the visible result is unchanged.
+func (p *Picture) CleanupTransparentArea() {
+	if p.pic != nil {
+		C.WebPCleanupTransparentArea(p.pic)
+	}
+}
+
+// CleanupTransparentAreaLossless set the color of fully transparent pixels to
+// zero to help the lossless compression. libwebp keep the function internal,
+// so it is implemented here, YUV pictures are left unchanged.
+func (p *Picture) CleanupTransparentAreaLossless() {
+	if p.pic == nil || p.pic.use_argb == 0 || p.pic.argb == nil {
+		return
+	}
+	w, h, stride := int(p.pic.width), int(p.pic.height), int(p.pic.argb_stride)
+	n := (h-1)*stride + w
+	argb := (*(*[1 << 28]uint32)(unsafe.Pointer(p.pic.argb)))[:n:n]
+	for y := 0; y < h; y++ {
+		row := argb[y*stride : y*stride+w]
+		for x, c := range row {
+			if c>>24 == 0 {
+				row[x] = 0
+			}
+		}
+	}
+}
+
+// BlendAlpha flatten the picture onto an opaque background color, the picture is opaque afterwards
+func (p *Picture) BlendAlpha(background color.Color) {
+	if p.pic == nil {
+		return
+	}
+	c := color.NRGBAModel.Convert(background).(color.NRGBA)
+	C.WebPBlendAlpha(p.pic, C.uint32_t(uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B)))
+}
+
+// StripAlpha drop the alpha plane of a YUV picture which is fully opaque despite
+// having one, return whether the picture is opaque. Opaque ARGB pictures need
+// nothing, the encoder skip their alpha already.
+func (p *Picture) StripAlpha() bool {
+	if p.pic == nil || p.HasTransparency() {
+		return false
+	}
+	if p.pic.use_argb == 0 && p.pic.colorspace&C.WEBP_CSP_ALPHA_BIT != 0 {
+		p.pic.colorspace &^= C.WEBP_CSP_ALPHA_BIT
+		p.pic.a = nil
+		p.pic.a_stride = 0
+	}
+	return true
+}
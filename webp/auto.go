@@ -0,0 +1,170 @@
+package webp
+
+import (
+	"image"
+	"sync"
+)
+
+type AutoMode int
+
+const (
+	AutoLossless AutoMode = iota
+	AutoNearLossless
+	AutoLossy
+)
+
+func (m AutoMode) String() string {
+	switch m {
+	case AutoLossless:
+		return "lossless"
+	case AutoNearLossless:
+		return "near-lossless"
+	case AutoLossy:
+		return "lossy"
+	}
+	return "unknown"
+}
+
+type AutoOptions struct {
+	// metric used to measure every trial against the source image
+	Metric DistortionMetric
+	// minimum score the chosen output must reach, in the unit of Metric.
+	// The lossy trial search the lowest Quality reaching it.
+	Threshold float32
+	// level passed to SetupLosslessPreset for the lossless and near-lossless trials
+	LosslessLevel int
+	// EncodeOptions.NearLossless of the near-lossless trial, 100 disable the trial
+	NearLossless int
+}
+
+func NewAutoOptions() *AutoOptions {
+	return &AutoOptions{
+		Metric:        MetricSSIM,
+		Threshold:     0.98,
+		LosslessLevel: LosslessDefaultLevel,
+		NearLossless:  60,
+	}
+}
+
+type AutoResult struct {
+	Mode AutoMode
+	Size int
+	// score of the chosen output, in the unit of the metric
+	Score float32
+	// Quality of the lossy trial, meaningful if Mode is AutoLossy
+	Quality float32
+}
+
+type autoTrial struct {
+	data   []byte
+	result AutoResult
+	err    error
+}
+
+// EncodeAuto encode img as lossless, near-lossless and lossy in parallel and
+// return the smallest output whose score reach auto.Threshold. The lossless
+// output is returned if no trial reach it. Other fields of opts are shared by
+// all the trials.
+func EncodeAuto(img image.Image, opts *EncodeOptions, auto *AutoOptions) ([]byte, *AutoResult, error) {
+	//img is never encoded, it stay untouched as the reference of every trial
+	var trials [3]autoTrial
+	var wg sync.WaitGroup
+	run := func(mode AutoMode, f func(t *autoTrial)) {
+		trials[mode].result.Mode = mode
+		if mode == AutoNearLossless && auto.NearLossless >= 100 {
+			trials[mode].err = errTrialSkipped
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(&trials[mode])
+		}()
+	}
+
+	losslessTrial := func(nearLossless int) func(t *autoTrial) {
+		return func(t *autoTrial) {
+			o := *opts
+			if t.err = o.SetupLosslessPreset(auto.LosslessLevel); t.err != nil {
+				return
+			}
+			o.NearLossless = nearLossless
+			if t.data, t.err = EncodeSlice(cloneIfMutable(img), &o); t.err != nil {
+				return
+			}
+			t.result.Score, t.err = autoScore(img, t.data, auto.Metric)
+		}
+	}
+	run(AutoLossless, losslessTrial(100))
+	run(AutoNearLossless, losslessTrial(auto.NearLossless))
+	run(AutoLossy, func(t *autoTrial) {
+		o := *opts
+		var r *QualitySearchResult
+		if t.data, r, t.err = EncodeToQuality(cloneIfMutable(img), auto.Metric, auto.Threshold, &o); t.err != nil {
+			return
+		}
+		t.result.Quality = r.Quality
+		//scored like the other trials so the modes are compared against the same reference
+		t.result.Score, t.err = autoScore(img, t.data, auto.Metric)
+	})
+	wg.Wait()
+
+	var best *autoTrial
+	for i := range trials {
+		t := &trials[i]
+		if t.err == errTrialSkipped {
+			continue
+		}
+		if t.err != nil {
+			return nil, nil, t.err
+		}
+		t.result.Size = len(t.data)
+		if t.result.Score >= auto.Threshold && (best == nil || t.result.Size < best.result.Size) {
+			best = t
+		}
+	}
+	if best == nil {
+		best = &trials[AutoLossless]
+	}
+	result := best.result
+	return best.data, &result, nil
+}
+
+type autoError string
+
+func (e autoError) Error() string { return string(e) }
+
+const errTrialSkipped = autoError("trial skipped")
+
+func autoScore(img image.Image, data []byte, metric DistortionMetric) (float32, error) {
+	decoded, err := DecodeSlice(data, NewDecOptions())
+	if err != nil {
+		return 0, err
+	}
+	d, err := Distortion(img, decoded, metric)
+	if err != nil {
+		return 0, err
+	}
+	return d.All, nil
+}
+
+// cloneIfMutable copy images whose memory is handed to libwebp directly and
+// may be modified in place while encoding, e.g. transparent pixels are
+// replaced. Every trial encode its own copy, so none of them race with or
+// alter the source image.
+func cloneIfMutable(img image.Image) image.Image {
+	switch m := img.(type) {
+	case *ARGBImg:
+		c := *m
+		c.Pix = append([]uint32(nil), m.Pix...)
+		return &c
+	case *NYCbCrA:
+		c := *m
+		c.Y = append([]uint8(nil), m.Y...)
+		c.Cb = append([]uint8(nil), m.Cb...)
+		c.Cr = append([]uint8(nil), m.Cr...)
+		c.A = append([]uint8(nil), m.A...)
+		return &c
+	}
+	return img
+}
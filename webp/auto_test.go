@@ -0,0 +1,91 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// the left half of the test images is transparent with garbage colors, which
+// libwebp replace in place while encoding unless opts.Exact is set
+
+func testARGB(w, h int) *ARGBImg {
+	m := NewARGB(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := uint32(0xff)
+			if x < w/2 {
+				a = 0
+			}
+			m.Pix[y*m.Stride+x] = a<<24 | uint32(x*7)<<16 | uint32(y*5)<<8 | uint32(x*y*31&0xff)
+		}
+	}
+	return m
+}
+
+func testNYCbCrA(w, h int) *NYCbCrA {
+	m := NewNYCbCrA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.Y[m.YOffset(x, y)] = uint8(16 + (x*y*31)%220)
+			m.Cb[m.COffset(x, y)] = uint8(16 + x*3)
+			m.Cr[m.COffset(x, y)] = uint8(16 + y*3)
+			if x >= w/2 {
+				m.A[m.AOffset(x, y)] = 0xff
+			}
+		}
+	}
+	return m
+}
+
+func TestEncodeAuto(t *testing.T) {
+	const w, h = 64, 64
+	argb := testARGB(w, h)
+	nycbcra := testNYCbCrA(w, h)
+	wantARGB := append([]uint32(nil), argb.Pix...)
+	wantPlanes := [][]uint8{
+		append([]uint8(nil), nycbcra.Y...),
+		append([]uint8(nil), nycbcra.Cb...),
+		append([]uint8(nil), nycbcra.Cr...),
+		append([]uint8(nil), nycbcra.A...),
+	}
+
+	opaque := testNYCbCrA(w, h)
+	for i := range opaque.A {
+		opaque.A[i] = 0xff
+	}
+
+	for _, img := range []image.Image{argb, nycbcra, opaque} {
+		ref := cloneIfMutable(img)
+		opts, err := NewEncOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, result, err := EncodeAuto(img, opts, NewAutoOptions())
+		if err != nil {
+			t.Fatalf("%T: %v", img, err)
+		}
+		if result.Size != len(data) {
+			t.Errorf("%T: Size %d, want %d", img, result.Size, len(data))
+		}
+		// the score must be measured against the source, not a copy a trial cleaned up
+		score, err := autoScore(ref, data, MetricSSIM)
+		if err != nil {
+			t.Fatalf("%T: %v", img, err)
+		}
+		if score != result.Score {
+			t.Errorf("%T: %s Score %v, want %v", img, result.Mode, result.Score, score)
+		}
+	}
+
+	for i := range wantARGB {
+		if argb.Pix[i] != wantARGB[i] {
+			t.Fatalf("ARGBImg pixel %d changed from %#x to %#x", i, wantARGB[i], argb.Pix[i])
+		}
+	}
+	for i, p := range [][]uint8{nycbcra.Y, nycbcra.Cb, nycbcra.Cr, nycbcra.A} {
+		if !bytes.Equal(p, wantPlanes[i]) {
+			t.Fatalf("NYCbCrA plane %d changed", i)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package webp
+
+import (
+	"image"
+	"image/color"
+)
+
+type AlphaUsage int
+
+const (
+	AlphaOpaque AlphaUsage = iota // every pixel is opaque
+	AlphaBinary                   // alpha is only 0 or 255
+	AlphaFull                     // semi transparent pixels are present
+)
+
+const (
+	// at most analyseSamples x analyseSamples pixels are inspected
+	analyseSamples = 256
+	// color count above which the image is considered continuous tone
+	analyseMaxColors = 256
+	// luma difference between neighbours counted as an edge
+	analyseEdgeThreshold = 48
+	// images with both sides up to this are considered icons
+	analyseIconSize = 128
+	// images with more pixels than this are considered photos rather than pictures
+	analysePhotoPixels = 1 << 20
+)
+
+// ContentAnalysis is the result of AnalyzeContent
+type ContentAnalysis struct {
+	Width, Height int
+	// number of distinct colors of the inspected pixels, capped to 257
+	Colors int
+	// fraction of inspected pixels which differ much from their right or bottom neighbour
+	EdgeDensity float32
+	Alpha       AlphaUsage
+
+	// recommendations
+	Preset   EncodePreset
+	Hint     ImageHint
+	Lossless bool
+}
+
+// AnalyzeContent inspect img and recommend the preset, hint and compression mode to encode it.
+// Large images are sampled on a regular grid so the cost is bounded.
+func AnalyzeContent(img image.Image) *ContentAnalysis {
+	rect := img.Bounds()
+	a := &ContentAnalysis{Width: rect.Dx(), Height: rect.Dy()}
+	if rect.Empty() {
+		a.Preset, a.Hint = PresetDefault, HintDefault
+		return a
+	}
+
+	stepX, stepY := analyseStep(a.Width), analyseStep(a.Height)
+	colors := make(map[color.NRGBA]struct{}, analyseMaxColors+1)
+	var samples, edges int
+	for y := rect.Min.Y; y < rect.Max.Y; y += stepY {
+		for x := rect.Min.X; x < rect.Max.X; x += stepX {
+			c := nrgbaAt(img, x, y)
+			if len(colors) <= analyseMaxColors {
+				colors[c] = struct{}{}
+			}
+			switch {
+			case c.A == 0 || c.A == 0xff:
+				if c.A == 0 && a.Alpha == AlphaOpaque {
+					a.Alpha = AlphaBinary
+				}
+			default:
+				a.Alpha = AlphaFull
+			}
+
+			l := analyseLuma(c)
+			if x+1 < rect.Max.X && absDiff(l, analyseLuma(nrgbaAt(img, x+1, y))) > analyseEdgeThreshold ||
+				y+1 < rect.Max.Y && absDiff(l, analyseLuma(nrgbaAt(img, x, y+1))) > analyseEdgeThreshold {
+				edges++
+			}
+			samples++
+		}
+	}
+	a.Colors = len(colors)
+	a.EdgeDensity = float32(edges) / float32(samples)
+	a.recommend()
+	return a
+}
+
+func (a *ContentAnalysis) recommend() {
+	few := a.Colors <= analyseMaxColors
+	switch {
+	case a.Width <= analyseIconSize && a.Height <= analyseIconSize:
+		a.Preset, a.Hint = PresetIcon, HintGraph
+		a.Lossless = few || a.Alpha != AlphaOpaque
+	case a.Colors <= 16 && a.EdgeDensity > 0.05:
+		a.Preset, a.Hint, a.Lossless = PresetText, HintGraph, true
+	case few:
+		a.Preset, a.Hint, a.Lossless = PresetDrawing, HintGraph, true
+	case a.EdgeDensity > 0.2:
+		a.Preset, a.Hint = PresetDrawing, HintPicture
+	case a.Width*a.Height > analysePhotoPixels:
+		a.Preset, a.Hint = PresetPhoto, HintPhoto
+	default:
+		a.Preset, a.Hint = PresetPicture, HintPicture
+	}
+}
+
+// EncOptions create EncodeOptions from the recommendations, quality is used by lossy mode only
+func (a *ContentAnalysis) EncOptions(quality float32) (*EncodeOptions, error) {
+	opts, err := NewEncOptionsByPreset(a.Preset, quality)
+	if err != nil {
+		return nil, err
+	}
+	if a.Lossless {
+		if err = opts.SetupLosslessPreset(LosslessDefaultLevel); err != nil {
+			return nil, err
+		}
+	}
+	opts.ImageHint = a.Hint
+	return opts, nil
+}
+
+// NewEncOptionsByContent is a shortcut of AnalyzeContent(img).EncOptions(quality)
+func NewEncOptionsByContent(img image.Image, quality float32) (*EncodeOptions, error) {
+	return AnalyzeContent(img).EncOptions(quality)
+}
+
+func analyseStep(n int) int {
+	if n <= analyseSamples {
+		return 1
+	}
+	return (n + analyseSamples - 1) / analyseSamples
+}
+
+func analyseLuma(c color.NRGBA) int {
+	return (int(c.R)*77 + int(c.G)*150 + int(c.B)*29) >> 8
+}
+
+func absDiff(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return m.NRGBAAt(x, y)
+	case *RGBImg:
+		c := m.RGBAAt(x, y)
+		return color.NRGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+	}
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
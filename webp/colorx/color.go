@@ -1,6 +1,9 @@
 package colorx
 
-import "image/color"
+import (
+	"image/color"
+	"math"
+)
 
 func RGBToBT601(r, g, b uint8) (uint8, uint8, uint8) {
 	/* ITU-R BT.601
@@ -54,6 +57,32 @@ func BT601ToRGB(y, cb, cr uint8) (uint32, uint32, uint32) {
 	return uint32(r<<8 | r), uint32(g<<8 | g), uint32(b<<8 | b)
 }
 
+// BT601ToGray expand a ITU-R BT.601 studio range luma [16..235] to full range gray [0..255]
+func BT601ToGray(y uint8) uint8 {
+	/* Gray = 255 / 219 * (Y-16) */
+	v := (int32(y)-16)*76284 + 32768
+	if uint32(v)&0xff000000 == 0 {
+		return uint8(v >> 16)
+	}
+	return uint8(^(v >> 31) & 0xff)
+}
+
+// BT601ChromaToFull expand a ITU-R BT.601 studio range chroma [16..240] to full range [0..255]
+func BT601ChromaToFull(c uint8) uint8 {
+	/* C = 255 / 224 * (C-128) + 128 */
+	v := (int32(c)-128)*74606 + 128<<16 + 32768
+	if uint32(v)&0xff000000 == 0 {
+		return uint8(v >> 16)
+	}
+	return uint8(^(v >> 31) & 0xff)
+}
+
+// RGBToGray use the same luma weights as color.GrayModel
+func RGBToGray(r, g, b uint8) uint8 {
+	/* Y = 0.299*R + 0.587*G + 0.114*B */
+	return uint8((19595*uint32(r) + 38470*uint32(g) + 7471*uint32(b) + 1<<15) >> 16)
+}
+
 type YCbCrBT601 struct {
 	Y, Cb, Cr uint8
 }
@@ -136,3 +165,45 @@ func (c RGB) RGBA() (r, g, b, a uint32) {
 	a = uint32(0xffff)
 	return
 }
+
+var srgbToLinearLUT = func() (lut [256]float32) {
+	for i := range lut {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			lut[i] = float32(v / 12.92)
+		} else {
+			lut[i] = float32(math.Pow((v+0.055)/1.055, 2.4))
+		}
+	}
+	return
+}()
+
+const linearToSRGBLUTSize = 1 << 14
+
+var linearToSRGBLUT = func() (lut [linearToSRGBLUTSize]uint8) {
+	for i := range lut {
+		v := float64(i) / (linearToSRGBLUTSize - 1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		lut[i] = uint8(v*255 + 0.5)
+	}
+	return
+}()
+
+// SRGBToLinear decode a sRGB sample to linear light in [0, 1]
+func SRGBToLinear(v uint8) float32 {
+	return srgbToLinearLUT[v]
+}
+
+// LinearToSRGB encode linear light to sRGB sample, v is clamped to [0, 1]
+func LinearToSRGB(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	} else if v >= 1 {
+		return 0xff
+	}
+	return linearToSRGBLUT[int(v*(linearToSRGBLUTSize-1)+0.5)]
+}
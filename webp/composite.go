@@ -0,0 +1,67 @@
+package webp
+
+import "image"
+
+// compositeOnto blend img over bg in gamma space like WebPBlendAlpha, bg is
+// aligned to the top-left of img and treated as black where it is transparent
+// or out of bounds. Return opaque image.RGBA if img is image.RGBA or
+// image.NRGBA, otherwise webp.RGBImg.
+func compositeOnto(img image.Image, bg image.Image) image.Image {
+	if m, ok := img.(*RGBImg); ok {
+		return m //already opaque
+	}
+
+	rect := img.Bounds()
+	w, h := rect.Dx(), rect.Dy()
+	var pix []uint8
+	var stride, bpp int
+	var out image.Image
+	switch img.(type) {
+	case *image.RGBA, *image.NRGBA:
+		m := image.NewRGBA(image.Rect(0, 0, w, h))
+		pix, stride, bpp, out = m.Pix, m.Stride, 4, m
+	default:
+		m := NewRGB(image.Rect(0, 0, w, h))
+		pix, stride, bpp, out = m.Pix, m.Stride, 3, m
+	}
+
+	//x, y are relative to the top-left of img
+	bgMin := bg.Bounds().Min
+	bgAt := func(x, y int) (uint32, uint32, uint32) {
+		r, g, b, _ := bg.At(bgMin.X+x, bgMin.Y+y).RGBA()
+		return r, g, b
+	}
+	if u, ok := bg.(*image.Uniform); ok {
+		r, g, b, _ := u.C.RGBA()
+		bgAt = func(int, int) (uint32, uint32, uint32) { return r, g, b }
+	}
+
+	nrgba, _ := img.(*image.NRGBA)
+	for y := 0; y < h; y++ {
+		row := pix[y*stride : y*stride+w*bpp]
+		for x, i := 0, 0; x < w; x, i = x+1, i+bpp {
+			var sr, sg, sb, sa uint32
+			if nrgba != nil {
+				//fast path for the common lossless with alpha case
+				c := nrgba.Pix[y*nrgba.Stride+x*4 : y*nrgba.Stride+x*4+4]
+				sa = uint32(c[3]) * 0x101
+				sr = uint32(c[0]) * 0x101 * sa / 0xffff
+				sg = uint32(c[1]) * 0x101 * sa / 0xffff
+				sb = uint32(c[2]) * 0x101 * sa / 0xffff
+			} else {
+				sr, sg, sb, sa = img.At(rect.Min.X+x, rect.Min.Y+y).RGBA()
+			}
+			if sa != 0xffff {
+				br, bG, bb := bgAt(x, y)
+				sr += br * (0xffff - sa) / 0xffff
+				sg += bG * (0xffff - sa) / 0xffff
+				sb += bb * (0xffff - sa) / 0xffff
+			}
+			row[i], row[i+1], row[i+2] = uint8(sr>>8), uint8(sg>>8), uint8(sb>>8)
+			if bpp == 4 {
+				row[i+3] = 0xff
+			}
+		}
+	}
+	return out
+}
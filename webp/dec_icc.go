@@ -0,0 +1,205 @@
+package webp
+
+import (
+	"fmt"
+	"github.com/mocukie/webp-go/webp/colorx"
+	"github.com/mocukie/webp-go/webp/icc"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// building an icc.Transform take tens of milliseconds, far more than converting
+// a typical image, so the recently used ones are kept. They are keyed by the
+// address of the target profile, a TargetProfile must not be modified once used.
+const maxCachedTransforms = 8
+
+type transformKey struct {
+	iccp   string
+	tagged bool
+	dst    *icc.Profile
+}
+
+var transforms = struct {
+	sync.Mutex
+	m map[transformKey]*icc.Transform
+}{m: make(map[transformKey]*icc.Transform)}
+
+// cachedTransform return the transform from the ICCP chunk data, or sRGB if
+// the image is not tagged, to dst
+func cachedTransform(data []byte, tagged bool, dst *icc.Profile) (*icc.Transform, error) {
+	key := transformKey{string(data), tagged, dst}
+	transforms.Lock()
+	t := transforms.m[key]
+	transforms.Unlock()
+	if t != nil {
+		return t, nil
+	}
+
+	src := icc.SRGB
+	if tagged {
+		var err error
+		if src, err = icc.Parse(data); err != nil {
+			return nil, err
+		}
+	}
+	t, err := icc.NewTransform(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	transforms.Lock()
+	if len(transforms.m) >= maxCachedTransforms {
+		for k := range transforms.m {
+			delete(transforms.m, k) //evict any one
+			break
+		}
+	}
+	transforms.m[key] = t
+	transforms.Unlock()
+	return t, nil
+}
+
+// convertProfile convert decoded pixels in place from the embedded ICC profile
+// to dst, untagged webp is treated as sRGB. The error of package icc is returned
+// if the embedded profile can not be parsed or is not supported, e.g. icc.ErrUnsupported
+// for a LUT based profile.
+func convertProfile(input []byte, img image.Image, dst *icc.Profile) (image.Image, error) {
+	data, err := GetMetadata(input, ICCP)
+	tagged := err == nil
+	if !tagged && dst == icc.SRGB {
+		return img, nil
+	}
+	t, err := cachedTransform(data, tagged, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m := img.(type) {
+	case *RGBImg:
+		t.ConvertPix(m.Pix, m.Stride, m.Rect.Dx(), m.Rect.Dy(), 3)
+	case *image.NRGBA:
+		t.ConvertPix(m.Pix, m.Stride, m.Rect.Dx(), m.Rect.Dy(), 4)
+	case *image.RGBA:
+		convertPremultiplied(t, m)
+	case *image.NRGBA64:
+		t.ConvertPix16(m.Pix, m.Stride, m.Rect.Dx(), m.Rect.Dy(), 8)
+	case *image.RGBA64:
+		convertPremultiplied64(t, m)
+	case *YCbCr:
+		convertYCbCr(t, &m.YCbCr, bt601ToRGB, colorx.RGBToBT601)
+	case *NYCbCrA:
+		convertYCbCr(t, &m.YCbCr, bt601ToRGB, colorx.RGBToBT601)
+	case *image.YCbCr:
+		convertYCbCr(t, m, color.YCbCrToRGB, color.RGBToYCbCr)
+	case *image.NYCbCrA:
+		convertYCbCr(t, &m.YCbCr, color.YCbCrToRGB, color.RGBToYCbCr)
+	case *image.Gray:
+		convertGray(t, m)
+	case *image.Alpha:
+		//no color to convert
+	default:
+		return nil, fmt.Errorf("TargetProfile is not supported for %T", img)
+	}
+	return img, nil
+}
+
+func convertPremultiplied(t *icc.Transform, m *image.RGBA) {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	for y := 0; y < h; y++ {
+		row := m.Pix[y*m.Stride : y*m.Stride+w*4]
+		for i := 0; i < len(row); i += 4 {
+			a := uint32(row[i+3])
+			if a == 0 {
+				continue
+			}
+			if a == 0xff {
+				row[i], row[i+1], row[i+2] = t.Convert(row[i], row[i+1], row[i+2])
+				continue
+			}
+			r, g, b := t.Convert(
+				uint8((uint32(row[i])*0xff+a/2)/a),
+				uint8((uint32(row[i+1])*0xff+a/2)/a),
+				uint8((uint32(row[i+2])*0xff+a/2)/a))
+			row[i] = uint8((uint32(r)*a + 0x7f) / 0xff)
+			row[i+1] = uint8((uint32(g)*a + 0x7f) / 0xff)
+			row[i+2] = uint8((uint32(b)*a + 0x7f) / 0xff)
+		}
+	}
+}
+
+func convertPremultiplied64(t *icc.Transform, m *image.RGBA64) {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	for y := 0; y < h; y++ {
+		row := m.Pix[y*m.Stride : y*m.Stride+w*8]
+		for i := 0; i < len(row); i += 8 {
+			a := uint32(row[i+6])<<8 | uint32(row[i+7])
+			if a == 0 {
+				continue
+			}
+			var c [3]uint32
+			for j := range c {
+				c[j] = uint32(row[i+2*j])<<8 | uint32(row[i+2*j+1])
+				if a != 0xffff {
+					c[j] = (c[j]*0xffff + a/2) / a
+				}
+				if c[j] > 0xffff {
+					c[j] = 0xffff
+				}
+			}
+			r, g, b := t.Convert16(uint16(c[0]), uint16(c[1]), uint16(c[2]))
+			c = [3]uint32{uint32(r), uint32(g), uint32(b)}
+			for j, v := range c {
+				if a != 0xffff {
+					v = (v*a + 0x7fff) / 0xffff
+				}
+				row[i+2*j], row[i+2*j+1] = uint8(v>>8), uint8(v)
+			}
+		}
+	}
+}
+
+// convertYCbCr convert every pixel through RGB with the given formulas, BT.601
+// limited range for webp.YCbCr and JFIF full range for image.YCbCr.
+// Chroma of a subsampled block is averaged.
+func convertYCbCr(t *icc.Transform, m *image.YCbCr, toRGB, fromRGB func(uint8, uint8, uint8) (uint8, uint8, uint8)) {
+	cb := make([]uint32, len(m.Cb))
+	cr := make([]uint32, len(m.Cr))
+	n := make([]uint32, len(m.Cb))
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			yi, ci := m.YOffset(x, y), m.COffset(x, y)
+			Y, U, V := fromRGB(t.Convert(toRGB(m.Y[yi], m.Cb[ci], m.Cr[ci])))
+			m.Y[yi] = Y
+			cb[ci] += uint32(U)
+			cr[ci] += uint32(V)
+			n[ci]++
+		}
+	}
+	for i, c := range n {
+		if c != 0 {
+			m.Cb[i] = uint8((cb[i] + c/2) / c)
+			m.Cr[i] = uint8((cr[i] + c/2) / c)
+		}
+	}
+}
+
+// convertGray convert the neutral color of every gray level, the result is its luma
+func convertGray(t *icc.Transform, m *image.Gray) {
+	var lut [256]uint8
+	for v := range lut {
+		lut[v] = colorx.RGBToGray(t.Convert(uint8(v), uint8(v), uint8(v)))
+	}
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	for y := 0; y < h; y++ {
+		row := m.Pix[y*m.Stride : y*m.Stride+w]
+		for i, v := range row {
+			row[i] = lut[v]
+		}
+	}
+}
+
+func bt601ToRGB(y, cb, cr uint8) (uint8, uint8, uint8) {
+	r, g, b := colorx.BT601ToRGB(y, cb, cr)
+	return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)
+}
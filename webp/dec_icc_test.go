@@ -0,0 +1,34 @@
+package webp
+
+import (
+	"github.com/mocukie/webp-go/webp/icc"
+	"testing"
+)
+
+func TestCachedTransform(t *testing.T) {
+	g := icc.GammaCurve(2.2)
+	gamma22 := &icc.Profile{Matrix: icc.SRGB.Matrix, TRC: [3]icc.Curve{g, g, g}}
+
+	a, err := cachedTransform(nil, false, gamma22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cachedTransform(nil, false, gamma22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("the transform was built again for the same profiles")
+	}
+
+	c, err := cachedTransform(nil, false, &icc.Profile{Matrix: gamma22.Matrix, TRC: gamma22.TRC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == a {
+		t.Error("the same transform is returned for another target profile")
+	}
+	if _, err = cachedTransform([]byte("not a profile"), true, gamma22); err != icc.ErrInvalid {
+		t.Errorf("invalid ICCP data: got error %v, want %v", err, icc.ErrInvalid)
+	}
+}
@@ -5,7 +5,10 @@ package webp
 #include <webp/decode.h>
 */
 import "C"
-import "image"
+import (
+	"github.com/mocukie/webp-go/webp/icc"
+	"image"
+)
 
 type DecCspMode int
 
@@ -29,6 +32,35 @@ var (
 	TypeNRGBA DecPixelFormat = decPixNRGBA
 	TypeYUV   DecPixelFormat = decPixYUV
 	TypeYUVA  DecPixelFormat = decPixYUVA
+	// luma only, return image.Gray. lossy webp keep the Y plane only, lossless webp convert from RGB
+	TypeGray DecPixelFormat = decPixGray
+	// alpha channel only, return image.Alpha. lossy webp keep the alpha plane only, lossless webp extract it from RGBA
+	TypeAlpha DecPixelFormat = decPixAlpha
+	// fancy upsampled 4:4:4 ITU-R BT.601, return webp.YCbCr/webp.NYCbCrA with YCbCrSubsampleRatio444
+	TypeYUV444 DecPixelFormat = decPixYUV444
+	// fancy upsampled 4:4:4 JFIF full range, return image.YCbCr/image.NYCbCrA with YCbCrSubsampleRatio444
+	TypeJFIF444 DecPixelFormat = decPixJFIF444
+	// 16 bit expanded from the decoded 8 bit samples, return image.RGBA64/image.NRGBA64
+	TypeRGBA64  DecPixelFormat = decPixRGBA64
+	TypeNRGBA64 DecPixelFormat = decPixNRGBA64
+	// same as TypeRGBA64/TypeNRGBA64, color samples are ordered dithered to reduce banding
+	TypeRGBA64Dither  DecPixelFormat = decPixRGBA64Dither
+	TypeNRGBA64Dither DecPixelFormat = decPixNRGBA64Dither
+)
+
+type ScaleMode int
+
+const (
+	// scale to Scale.Dx() x Scale.Dy() exactly, aspect ratio is ignored
+	ScaleExact ScaleMode = iota
+	// scale to fit inside Scale keeping aspect ratio, a zero side of Scale is unconstrained
+	ScaleContain
+	// scale to fill Scale keeping aspect ratio, the overflow is cropped around the center
+	ScaleCover
+	// scale to Scale.Dx() wide keeping aspect ratio
+	ScaleFitWidth
+	// scale to Scale.Dy() high keeping aspect ratio
+	ScaleFitHeight
 )
 
 type DecodeOptions struct {
@@ -36,18 +68,24 @@ type DecodeOptions struct {
 	NoFancyUpsampling      bool            // if true, use faster pointwise upsampler
 	Crop                   image.Rectangle // do cropping if not empty, this is applied _first_
 	Scale                  image.Rectangle // do scaling if not empty,  this is applied _afterward_
+	ScaleMode              ScaleMode       // how Scale is fitted to the (cropped) image
+	ShrinkOnly             bool            // if true, never scale up, a Scale which enlarge a side or keep the size is skipped (with the crop of ScaleCover)
 	UseThreads             bool            // if true, use multi-threaded decoding
 	DitheringStrength      int             // dithering strength (0=Off, 100=full)
 	Flip                   bool            // flip output vertically
 	AlphaDitheringStrength int             // alpha dithering strength in [0..100]
 	ImageType              DecPixelFormat  // decoded image type
+	AutoOrient             bool            // rotate/flip by EXIF orientation, this is applied after Crop and Scale
+	TargetProfile          *icc.Profile    // if not nil, convert pixels from the embedded ICC profile (sRGB if absent) to it, decoding fail if the embedded profile is not supported by package icc. It must not be modified once used
+	LinearResample         ResampleFilter  // if set, Scale is done in linear light with this filter and ImageType is ignored, output type is the same as TypeStd even if no scaling is done
+	Background             image.Image     // if not nil, composite onto it (image.NewUniform for a solid color), output is opaque image.RGBA for image.RGBA/image.NRGBA, otherwise webp.RGBImg
 }
 
 func NewDecOptions() *DecodeOptions {
 	return &DecodeOptions{ImageType: TypeAuto, UseThreads: true}
 }
 
-func (opts *DecodeOptions) assign(c *C.WebPDecoderOptions) {
+func (opts *DecodeOptions) assign(c *C.WebPDecoderOptions, f *C.WebPBitstreamFeatures) {
 	c.bypass_filtering = bool2CInt(opts.BypassFiltering)
 	c.no_fancy_upsampling = bool2CInt(opts.NoFancyUpsampling)
 	c.use_threads = bool2CInt(opts.UseThreads)
@@ -55,19 +93,90 @@ func (opts *DecodeOptions) assign(c *C.WebPDecoderOptions) {
 	c.flip = bool2CInt(opts.Flip)
 	c.alpha_dithering_strength = C.int(opts.AlphaDitheringStrength)
 
+	src := image.Rect(0, 0, int(f.width), int(f.height))
 	if !opts.Crop.Empty() {
-		c.use_cropping = 1
-		c.crop_left = C.int(opts.Crop.Min.X)
-		c.crop_top = C.int(opts.Crop.Min.Y)
-		c.crop_width = C.int(opts.Crop.Dx())
-		c.crop_height = C.int(opts.Crop.Dy())
+		src = opts.Crop
+		setCropping(c, src)
+	}
+
+	if opts.ScaleMode == ScaleExact {
+		if !opts.Scale.Empty() {
+			setScaling(c, opts.Scale.Dx(), opts.Scale.Dy(), src, opts.ShrinkOnly)
+		}
+		return
+	}
+
+	sw, sh := src.Dx(), src.Dy()
+	dw, dh := opts.Scale.Dx(), opts.Scale.Dy()
+	if sw <= 0 || sh <= 0 || (dw <= 0 && dh <= 0) {
+		return
+	}
+
+	mode := opts.ScaleMode
+	if mode == ScaleContain || (mode == ScaleCover && (dw <= 0 || dh <= 0)) {
+		//pick the side which limit the scale factor
+		if dh <= 0 || (dw > 0 && dw*sh <= dh*sw) {
+			mode = ScaleFitWidth
+		} else {
+			mode = ScaleFitHeight
+		}
 	}
 
-	if !opts.Scale.Empty() {
-		c.use_scaling = 1
-		c.scaled_width = C.int(opts.Scale.Dx())
-		c.scaled_height = C.int(opts.Scale.Dy())
+	switch mode {
+	case ScaleFitWidth:
+		if dw > 0 {
+			setScaling(c, dw, scaleSide(sh, dw, sw), src, opts.ShrinkOnly)
+		}
+	case ScaleFitHeight:
+		if dh > 0 {
+			setScaling(c, scaleSide(sw, dh, sh), dh, src, opts.ShrinkOnly)
+		}
+	case ScaleCover:
+		//crop the source to the aspect ratio of Scale, then scale the rest exactly
+		cw, ch := sw, sh
+		if dw*sh >= dh*sw {
+			ch = scaleSide(dh, sw, dw)
+		} else {
+			cw = scaleSide(dw, sh, dh)
+		}
+		off := src.Min.Add(image.Pt((sw-cw)/2, (sh-ch)/2))
+		src = image.Rectangle{Min: off, Max: off.Add(image.Pt(cw, ch))}
+		if opts.ShrinkOnly && !shrinks(dw, dh, src) {
+			return
+		}
+		setCropping(c, src)
+		setScaling(c, dw, dh, src, opts.ShrinkOnly)
+	}
+}
+
+// scaleSide return round(v * num / den), at least 1
+func scaleSide(v, num, den int) int {
+	if r := (v*num + den/2) / den; r > 0 {
+		return r
+	}
+	return 1
+}
+
+func setCropping(c *C.WebPDecoderOptions, r image.Rectangle) {
+	c.use_cropping = 1
+	c.crop_left = C.int(r.Min.X)
+	c.crop_top = C.int(r.Min.Y)
+	c.crop_width = C.int(r.Dx())
+	c.crop_height = C.int(r.Dy())
+}
+
+// shrinks report whether scaling src to w x h reduce a side without enlarging the other
+func shrinks(w, h int, src image.Rectangle) bool {
+	return w <= src.Dx() && h <= src.Dy() && (w < src.Dx() || h < src.Dy())
+}
+
+func setScaling(c *C.WebPDecoderOptions, w, h int, src image.Rectangle, shrinkOnly bool) {
+	if shrinkOnly && !shrinks(w, h, src) {
+		return
 	}
+	c.use_scaling = 1
+	c.scaled_width = C.int(w)
+	c.scaled_height = C.int(h)
 }
 
 type BitStreamFormat int
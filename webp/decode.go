@@ -16,6 +16,10 @@ import (
 
 const webpHeaderSize = 30 //riff(12) + VP8X(8)? + (VP8(10) or VP8L(5))
 
+// ICCP or unknown chunks may precede the image data, so the header probing
+// window grows up to this size
+const webpMaxHeaderSize = 16 << 20
+
 func DecoderVersion() Version {
 	return Version(C.WebPGetDecoderVersion())
 }
@@ -25,13 +29,7 @@ func Decode(r io.Reader) (image.Image, error) {
 }
 
 func DecodeConfig(r io.Reader) (image.Config, error) {
-	data := make([]byte, webpHeaderSize)
-	_, err := io.ReadFull(r, data)
-	if err != nil {
-		return image.Config{}, err
-	}
-
-	f, err := GetBitstreamFeatures(data)
+	f, err := probeBitstreamFeatures(r)
 	if err != nil {
 		return image.Config{}, err
 	}
@@ -63,44 +61,84 @@ func DecodeEX(r io.Reader, opts *DecodeOptions) (image.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	return decode(data, opts)
+	img, _, err := decode(data, opts)
+	return img, err
 }
 
 func DecodeSlice(data []byte, opts *DecodeOptions) (image.Image, error) {
+	img, _, err := decode(data, opts)
+	return img, err
+}
+
+// DecodeSliceOrient is like DecodeSlice but also report the EXIF orientation
+// which was applied to the image, OrientNormal if opts.AutoOrient is false.
+func DecodeSliceOrient(data []byte, opts *DecodeOptions) (image.Image, Orientation, error) {
 	return decode(data, opts)
 }
 
+// probeBitstreamFeatures read from r, doubling the read window until the
+// bitstream features can be parsed or webpMaxHeaderSize is reached
+func probeBitstreamFeatures(r io.Reader) (*BitstreamFeatures, error) {
+	var data []byte
+	for size := webpHeaderSize; ; size *= 2 {
+		if size > webpMaxHeaderSize {
+			size = webpMaxHeaderSize
+		}
+		buf := make([]byte, size)
+		copy(buf, data)
+		n, err := io.ReadFull(r, buf[len(data):])
+		data = buf[:len(data)+n]
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && (!eof || len(data) == 0) {
+			return nil, err
+		}
+
+		var f C.WebPBitstreamFeatures
+		code := getFeatures(data, &f)
+		if code == VP8StatusOk {
+			return newBitstreamFeatures(&f), nil
+		}
+		if code != VP8StatusNotEnoughData || eof || size == webpMaxHeaderSize {
+			return nil, code.error("could not get bits stream features, ")
+		}
+	}
+}
+
 func GetBitstreamFeatures(data []byte) (*BitstreamFeatures, error) {
-	cData, cSize := bytesGetCPtr(data)
 	var f C.WebPBitstreamFeatures
-	if code := VP8StatusCode(C.WebPGetFeatures(cData, cSize, &f)); code != VP8StatusOk {
+	if code := getFeatures(data, &f); code != VP8StatusOk {
 		return nil, code.error("could not get bits stream features, ")
 	}
+	return newBitstreamFeatures(&f), nil
+}
+
+func getFeatures(data []byte, f *C.WebPBitstreamFeatures) VP8StatusCode {
+	if len(data) == 0 {
+		return VP8StatusNotEnoughData
+	}
+	cData, cSize := bytesGetCPtr(data)
+	return VP8StatusCode(C.WebPGetFeatures(cData, cSize, f))
+}
+
+func newBitstreamFeatures(f *C.WebPBitstreamFeatures) *BitstreamFeatures {
 	return &BitstreamFeatures{
 		Width:        int(f.width),
 		Height:       int(f.height),
 		HasAlpha:     int(f.has_alpha) == 1,
 		HasAnimation: int(f.has_animation) == 1,
 		Format:       BitStreamFormat(f.format),
-	}, nil
-}
-
-func decode(input []byte, opts *DecodeOptions) (image.Image, error) {
-	cData, cSize := bytesGetCPtr(input)
-	config := &C.WebPDecoderConfig{}
-	if code := VP8StatusCode(C.WebPGetFeatures(cData, cSize, &config.input)); code != VP8StatusOk {
-		return nil, code.error("could not get bits stream features, ")
 	}
-	opts.assign(&config.options)
+}
 
-	var img image.Image
-	var width, height = calcOutputSize(config)
-	img = opts.ImageType(config, width, height)
-	if code := VP8StatusCode(C.WebPDecode(cData, cSize, config)); code != VP8StatusOk {
-		return nil, code.error("WebPDecode")
-	}
+func decode(input []byte, opts *DecodeOptions) (image.Image, Orientation, error) {
+	return NewDecoder(opts).decode(input)
+}
 
-	return img, nil
+// pixConverter is implemented by intermediate decode buffers that must be
+// converted into the final image once WebPDecode has filled them.
+type pixConverter interface {
+	image.Image
+	convert() image.Image
 }
 
 func decPixAuto(config *C.WebPDecoderConfig, width, height int) image.Image {
@@ -162,6 +200,196 @@ func decPixYUVA(config *C.WebPDecoderConfig, width, height int) image.Image {
 	return img
 }
 
+func decPixYUV444(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return newYUV444(config, width, height, false)
+}
+
+func decPixJFIF444(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return newYUV444(config, width, height, true)
+}
+
+func newYUV444(config *C.WebPDecoderConfig, width, height int, jfif bool) image.Image {
+	if int(config.input.has_alpha) == 1 {
+		return yuv444{decPixYUVA(config, width, height), jfif}
+	}
+	return yuv444{decPixYUV(config, width, height), jfif}
+}
+
+// yuv444 decode to 4:2:0 and upsample the chroma planes afterward
+type yuv444 struct {
+	image.Image // *YCbCr or *NYCbCrA
+	jfif        bool
+}
+
+func (p yuv444) convert() image.Image {
+	switch m := p.Image.(type) {
+	case *YCbCr:
+		y := upsample420(&m.YCbCr)
+		if p.jfif {
+			toJFIF(y)
+			return y
+		}
+		return &YCbCr{YCbCr: *y}
+	case *NYCbCrA:
+		a := image.NYCbCrA{YCbCr: *upsample420(&m.YCbCr), A: m.A, AStride: m.AStride}
+		if p.jfif {
+			toJFIF(&a.YCbCr)
+			return &a
+		}
+		return &NYCbCrA{NYCbCrA: a}
+	}
+	return p.Image
+}
+
+func decPixRGBA64(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return expand16{decPixNRGBA(config, width, height).(*image.NRGBA), true, false}
+}
+
+func decPixNRGBA64(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return expand16{decPixNRGBA(config, width, height).(*image.NRGBA), false, false}
+}
+
+func decPixRGBA64Dither(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return expand16{decPixNRGBA(config, width, height).(*image.NRGBA), true, true}
+}
+
+func decPixNRGBA64Dither(config *C.WebPDecoderConfig, width, height int) image.Image {
+	return expand16{decPixNRGBA(config, width, height).(*image.NRGBA), false, true}
+}
+
+// 4x4 Bayer matrix for ordered dithering
+var bayer4x4 = [4][4]int32{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// expand16 decode to image.NRGBA then expand samples to 16 bit, color samples
+// are optionally offset by an ordered dither within one 8 bit step
+type expand16 struct {
+	*image.NRGBA
+	premultiplied bool
+	dither        bool
+}
+
+func (p expand16) convert() image.Image {
+	src := p.NRGBA
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	pix := make([]uint8, w*h*8)
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		dst := pix[y*w*8 : (y+1)*w*8]
+		for x := 0; x < w; x++ {
+			var d int32
+			if p.dither {
+				//offset in (-0.5, 0.5) of a 8 bit step
+				d = (2*bayer4x4[y&3][x&3] - 15) * 0x101 / 32
+			}
+			a := uint32(row[x*4+3]) * 0x101
+			for c := 0; c < 3; c++ {
+				v := int32(row[x*4+c])*0x101 + d
+				if v < 0 {
+					v = 0
+				} else if v > 0xffff {
+					v = 0xffff
+				}
+				u := uint32(v)
+				if p.premultiplied {
+					u = u * a / 0xffff
+				}
+				dst[x*8+c*2] = uint8(u >> 8)
+				dst[x*8+c*2+1] = uint8(u)
+			}
+			dst[x*8+6] = uint8(a >> 8)
+			dst[x*8+7] = uint8(a)
+		}
+	}
+	if p.premultiplied {
+		return &image.RGBA64{Pix: pix, Stride: w * 8, Rect: src.Rect}
+	}
+	return &image.NRGBA64{Pix: pix, Stride: w * 8, Rect: src.Rect}
+}
+
+func decPixGray(config *C.WebPDecoderConfig, width, height int) image.Image {
+	if BitStreamFormat(config.input.format) != FormatLossy {
+		rgb := NewRGB(image.Rect(0, 0, width, height))
+		setupRGBBuf(config, rgb.Pix, rgb.Stride, ModeRGB)
+		return grayFromRGB{rgb}
+	}
+
+	//decode Y plane straight into the gray image, chroma planes are discarded
+	gray := image.NewGray(image.Rect(0, 0, width, height))
+	cw, ch := (width+1)/2, (height+1)/2
+	uv := make([]uint8, 2*cw*ch)
+	setupYUVABuf(config, gray.Pix, uv[:cw*ch], uv[cw*ch:], nil, gray.Stride, cw, -1, ModeYUV)
+	return grayFromYUV{gray, uv}
+}
+
+type grayFromYUV struct {
+	*image.Gray
+	uv []uint8 // keep the discarded chroma planes alive until WebPDecode is done
+}
+
+func (p grayFromYUV) convert() image.Image {
+	for i, y := range p.Pix {
+		p.Pix[i] = colorx.BT601ToGray(y)
+	}
+	return p.Gray
+}
+
+type grayFromRGB struct {
+	*RGBImg
+}
+
+func (p grayFromRGB) convert() image.Image {
+	rect := p.Rect
+	gray := image.NewGray(rect)
+	for i, j := 0, 0; i < len(gray.Pix); i, j = i+1, j+3 {
+		gray.Pix[i] = colorx.RGBToGray(p.Pix[j], p.Pix[j+1], p.Pix[j+2])
+	}
+	return gray
+}
+
+func decPixAlpha(config *C.WebPDecoderConfig, width, height int) image.Image {
+	//lossless is decoded as ARGB, converting it to yuv only to discard the planes is wasted work
+	if BitStreamFormat(config.input.format) != FormatLossy {
+		nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+		setupRGBBuf(config, nrgba.Pix, nrgba.Stride, ModeNRGBA)
+		return alphaFromNRGBA{nrgba}
+	}
+
+	//decoder write alpha plane straight into image.Alpha, luma and chroma planes are discarded.
+	//opaque webp get a fully opaque alpha plane.
+	alpha := image.NewAlpha(image.Rect(0, 0, width, height))
+	cw, ch := (width+1)/2, (height+1)/2
+	yuv := make([]uint8, width*height+2*cw*ch)
+	y, u, v := yuv[:width*height], yuv[width*height:width*height+cw*ch], yuv[width*height+cw*ch:]
+	setupYUVABuf(config, y, u, v, alpha.Pix, width, cw, alpha.Stride, ModeYUVA)
+	return alphaFromYUVA{alpha, yuv}
+}
+
+type alphaFromYUVA struct {
+	*image.Alpha
+	yuv []uint8 // keep the discarded planes alive until WebPDecode is done
+}
+
+func (p alphaFromYUVA) convert() image.Image {
+	return p.Alpha
+}
+
+type alphaFromNRGBA struct {
+	*image.NRGBA
+}
+
+func (p alphaFromNRGBA) convert() image.Image {
+	alpha := image.NewAlpha(p.Rect)
+	for i, j := 0, 3; i < len(alpha.Pix); i, j = i+1, j+4 {
+		alpha.Pix[i] = p.Pix[j]
+	}
+	return alpha
+}
+
 func setupRGBBuf(config *C.WebPDecoderConfig, pix []uint8, stride int, mode DecCspMode) {
 	config.output.colorspace = C.WEBP_CSP_MODE(mode)
 	config.output.is_external_memory = C.int(1)
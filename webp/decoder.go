@@ -0,0 +1,98 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include <webp/decode.h>
+*/
+import "C"
+import "image"
+
+// Decoder decode many webp with the same options. The prepared decoder config
+// and the output buffer are reused between calls as long as the bitstream
+// features (size, format and alpha) don't change, e.g. when cropping the same
+// region from images of the same size.
+//
+// A Decoder must not be used concurrently, but it holds no per goroutine
+// state, so it is safe to keep Decoders in a sync.Pool.
+type Decoder struct {
+	opts     DecodeOptions
+	config   C.WebPDecoderConfig
+	features C.WebPBitstreamFeatures // features of the next input
+	buf      image.Image             // output buffer prepared for config.input, nil if not prepared
+}
+
+// NewDecoder create a Decoder with a copy of opts
+func NewDecoder(opts *DecodeOptions) *Decoder {
+	return &Decoder{opts: *opts}
+}
+
+// Decode decode data with the options of the Decoder. The returned image may
+// share memory with the Decoder, it is overwritten by the next call of Decode.
+func (d *Decoder) Decode(data []byte) (image.Image, error) {
+	img, _, err := d.decode(data)
+	return img, err
+}
+
+func (d *Decoder) decode(input []byte) (image.Image, Orientation, error) {
+	cData, cSize := bytesGetCPtr(input)
+	if code := VP8StatusCode(C.WebPGetFeatures(cData, cSize, &d.features)); code != VP8StatusOk {
+		return nil, OrientUndefined, code.error("could not get bits stream features, ")
+	}
+
+	if d.buf == nil || !sameFeatures(&d.features, &d.config.input) {
+		d.config = C.WebPDecoderConfig{input: d.features}
+		d.opts.assign(&d.config.options, &d.config.input)
+		var width, height = calcOutputSize(&d.config)
+		if d.opts.LinearResample != ResampleNone && int(d.config.options.use_scaling) == 1 {
+			//decode at the cropped size, scaling is done in Go
+			d.config.options.use_scaling = 0
+			srcW, srcH := calcOutputSize(&d.config)
+			d.buf = newLinearScaler(&d.config, srcW, srcH, width, height, d.opts.LinearResample)
+		} else if d.opts.LinearResample != ResampleNone {
+			//no scaling, keep the output type of the resampled image
+			d.buf = decPixStd(&d.config, width, height)
+		} else {
+			d.buf = d.opts.ImageType(&d.config, width, height)
+		}
+	}
+
+	//WebPDecode flip the output buffer by moving the plane pointers and negating the
+	//strides, and undo it only if decoding succeed. Restore it or the next call
+	//after a failed one would flip it back
+	output := d.config.output
+	code := VP8StatusCode(C.WebPDecode(cData, cSize, &d.config))
+	d.config.output = output
+	if code != VP8StatusOk {
+		return nil, OrientUndefined, code.error("WebPDecode")
+	}
+
+	img := d.buf
+	if c, ok := img.(pixConverter); ok {
+		img = c.convert()
+	}
+
+	if d.opts.TargetProfile != nil {
+		var err error
+		if img, err = convertProfile(input, img, d.opts.TargetProfile); err != nil {
+			return nil, OrientUndefined, err
+		}
+	}
+
+	orient := OrientNormal
+	if d.opts.AutoOrient {
+		//a broken EXIF chunk should not fail an image which was decoded
+		if o, err := GetOrientation(input); err == nil {
+			orient = o
+			img = applyOrientation(img, orient)
+		}
+	}
+
+	if d.opts.Background != nil {
+		img = compositeOnto(img, d.opts.Background)
+	}
+	return img, orient, nil
+}
+
+func sameFeatures(a, b *C.WebPBitstreamFeatures) bool {
+	return a.width == b.width && a.height == b.height && a.has_alpha == b.has_alpha && a.format == b.format
+}
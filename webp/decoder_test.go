@@ -0,0 +1,133 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func testWebP(tb testing.TB, w, h int, lossless bool) []byte {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := m.PixOffset(x, y)
+			m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = uint8(x), uint8(y), uint8(x*y*31), 0xff
+		}
+	}
+	opts, err := NewEncOptions()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	opts.Lossless = lossless
+	data, err := EncodeSlice(m, opts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// testTruncate cut the image chunk of a simple format webp to n bytes, the
+// container sizes are fixed so the headers still parse and decoding fail in the pixels
+func testTruncate(data []byte, n int) []byte {
+	b := append([]byte(nil), data[:20+n]...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	binary.LittleEndian.PutUint32(b[16:], uint32(n))
+	return b
+}
+
+func TestDecoderFlip(t *testing.T) {
+	const w, h = 64, 64
+	data := testWebP(t, w, h, true)
+	opts := NewDecOptions()
+	opts.ImageType = TypeNRGBA
+	want, err := DecodeSlice(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// libwebp undo the flip of the output buffer after a successful decode only,
+	// a decode failing after the buffer is set up must not make the Decoder alternate
+	truncated := testTruncate(data, (len(data)-20)/2&^1)
+	opts.Flip = true
+	d := NewDecoder(opts)
+	for i := 0; i < 3; i++ {
+		if _, err := d.Decode(truncated); err == nil {
+			t.Fatal("decoding truncated data succeeded")
+		}
+		img, err := d.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, w := img.(*image.NRGBA), want.(*image.NRGBA)
+		for y := 0; y < h; y++ {
+			if !bytes.Equal(got.Pix[y*got.Stride:(y+1)*got.Stride], w.Pix[(h-1-y)*w.Stride:(h-y)*w.Stride]) {
+				t.Fatalf("call %d: row %d is not flipped", i, y)
+			}
+		}
+	}
+}
+
+func TestDecodeShrinkOnly(t *testing.T) {
+	data := testWebP(t, 64, 64, true)
+	tests := []struct {
+		mode  ScaleMode
+		scale image.Rectangle
+		want  image.Rectangle
+	}{
+		{ScaleExact, image.Rect(0, 0, 64, 32), image.Rect(0, 0, 64, 32)},
+		{ScaleExact, image.Rect(0, 0, 32, 32), image.Rect(0, 0, 32, 32)},
+		{ScaleExact, image.Rect(0, 0, 64, 64), image.Rect(0, 0, 64, 64)},
+		{ScaleExact, image.Rect(0, 0, 80, 32), image.Rect(0, 0, 64, 64)},
+		{ScaleCover, image.Rect(0, 0, 32, 16), image.Rect(0, 0, 32, 16)},
+		{ScaleCover, image.Rect(0, 0, 128, 64), image.Rect(0, 0, 64, 64)},
+	}
+	for _, tt := range tests {
+		opts := NewDecOptions()
+		opts.ScaleMode, opts.Scale, opts.ShrinkOnly = tt.mode, tt.scale, true
+		img, err := DecodeSlice(data, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != tt.want {
+			t.Errorf("mode %d scale %v: got %v, want %v", tt.mode, tt.scale, img.Bounds(), tt.want)
+		}
+	}
+}
+
+func TestDecodeLinearResampleType(t *testing.T) {
+	data := testWebP(t, 64, 64, false)
+	for _, scale := range []image.Rectangle{{}, image.Rect(0, 0, 128, 128), image.Rect(0, 0, 32, 32)} {
+		opts := NewDecOptions()
+		opts.ImageType, opts.LinearResample, opts.Scale, opts.ShrinkOnly = TypeRGB, ResampleLanczos3, scale, true
+		img, err := DecodeSlice(data, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := img.(*image.RGBA); !ok {
+			t.Errorf("scale %v: got %T, want *image.RGBA", scale, img)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	data := testWebP(b, 512, 512, false)
+	opts := NewDecOptions()
+	b.Run("DecodeSlice", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := DecodeSlice(data, opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Decoder", func(b *testing.B) {
+		d := NewDecoder(opts)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.Decode(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
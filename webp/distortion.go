@@ -0,0 +1,136 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include "webp.h"
+*/
+import "C"
+import (
+	"errors"
+	"image"
+	"math"
+	"unsafe"
+)
+
+type DistortionMetric int
+
+const (
+	MetricPSNR DistortionMetric = 0
+	MetricSSIM DistortionMetric = 1
+	MetricLSIM DistortionMetric = 2
+)
+
+// toDB convert a score in the unit of the metric to dB as reported by libwebp,
+// SSIM is in [0, 1], PSNR and LSIM are already in dB.
+func (m DistortionMetric) toDB(score float32) float32 {
+	if m != MetricSSIM {
+		return score
+	}
+	if score >= 1 {
+		return 99
+	}
+	return float32(-10 * math.Log10(1-float64(score)))
+}
+
+func (m DistortionMetric) fromDB(db float32) float32 {
+	if m != MetricSSIM {
+		return db
+	}
+	return float32(1 - math.Pow(10, -float64(db)/10))
+}
+
+// DistortionResult hold scores in the unit of the metric, SSIM in [0, 1],
+// PSNR and LSIM in dB (99 for identical channels)
+type DistortionResult struct {
+	R, G, B, A float32
+	All        float32
+}
+
+// Distortion compute the distortion of b against a with WebPPictureDistortion,
+// a and b must have the same size. Any image Encode accept can be measured,
+// the pixels are imported the same way as Encode does, losslessly.
+func Distortion(a, b image.Image, metric DistortionMetric) (*DistortionResult, error) {
+	if a.Bounds().Dx() != b.Bounds().Dx() || a.Bounds().Dy() != b.Bounds().Dy() {
+		return nil, VP8EncErrorBadDimension
+	}
+
+	opts := &EncodeOptions{Lossless: true}
+	var picA, picB C.WebPPicture
+	defer C.WebPPictureFree(&picA)
+	defer C.WebPPictureFree(&picB)
+
+	holderA, err := webpPictureImport(&picA, a, opts)
+	if err != nil {
+		return nil, err
+	}
+	holderB, err := webpPictureImport(&picB, b, opts)
+	if err != nil {
+		return nil, err
+	}
+	if holderA == nil {
+		holderA = &C.PixMemHolder{}
+	}
+	if holderB == nil {
+		holderB = &C.PixMemHolder{}
+	}
+
+	var result [5]C.float
+	if int(C.GoWebPPictureDistortionUseGoMem(&picA, *holderA, &picB, *holderB, C.int(metric), &result[0])) == 0 {
+		return nil, errors.New("WebPPictureDistortion failed")
+	}
+	// results are reported as B, G, R, A, All
+	return &DistortionResult{
+		B:   metric.fromDB(float32(result[0])),
+		G:   metric.fromDB(float32(result[1])),
+		R:   metric.fromDB(float32(result[2])),
+		A:   metric.fromDB(float32(result[3])),
+		All: metric.fromDB(float32(result[4])),
+	}, nil
+}
+
+// pictureDistortion return B, G, R, A and All distortion in dB
+func pictureDistortion(src, ref *C.WebPPicture, metric DistortionMetric) ([5]float32, error) {
+	var result [5]C.float
+	var out [5]float32
+	if int(C.WebPPictureDistortion(src, ref, C.int(metric), &result[0])) == 0 {
+		return out, errors.New("WebPPictureDistortion failed")
+	}
+	for i := range out {
+		out[i] = float32(result[i])
+	}
+	return out, nil
+}
+
+// newARGBPicture allocate a C owned picture, it must be released by freePicture
+func newARGBPicture(rgba *C.uint8_t, width, height, stride int) (*C.WebPPicture, error) {
+	pic := C.GoAllocWebPPicture()
+	if pic == nil {
+		return nil, VP8EncErrorOutOfMemory
+	}
+	pic.width = C.int(width)
+	pic.height = C.int(height)
+	pic.use_argb = C.int(1)
+	if int(C.WebPPictureImportRGBA(pic, rgba, C.int(stride))) == 0 {
+		err := VP8EncodeError(pic.error_code)
+		freePicture(pic)
+		return nil, err
+	}
+	return pic, nil
+}
+
+func freePicture(pic *C.WebPPicture) {
+	C.WebPPictureFree(pic)
+	C.free(unsafe.Pointer(pic))
+}
+
+// newPictureFromWebP decode webp data into a C owned picture
+func newPictureFromWebP(data []byte) (*C.WebPPicture, error) {
+	cData, cSize := bytesGetCPtr(data)
+	var w, h C.int
+	rgba := C.WebPDecodeRGBA(cData, cSize, &w, &h)
+	if rgba == nil {
+		return nil, VP8StatusBitstreamError.error("WebPDecodeRGBA")
+	}
+	defer C.WebPFree(unsafe.Pointer(rgba))
+	return newARGBPicture(rgba, int(w), int(h), int(w)*4)
+}
@@ -5,7 +5,7 @@ Require
 
 gcc
 
-libwebp 1.1.0
+libwebp 1.1.0, EncodeOptions.QMin and QMax need libwebp 1.2+
 
 Usage
 
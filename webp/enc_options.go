@@ -111,6 +111,11 @@ type EncodeOptions struct {
 	UseDeltaPalette int
 	// if needed, use sharp (and slow) RGB->YUV conversion
 	UseSharpYUV bool
+	// minimum and maximum permissible quality factor, in [0..100]. QMax 0 means
+	// 100, so both 0 is the default range. Only honored if SupportsQRange report true.
+	QMin, QMax int
+	// per macroblock info captured by EncodeWithStats for lossy encoding, not part of WebPConfig
+	ExtraInfo ExtraInfoType
 }
 
 func (opts *EncodeOptions) from(c *C.WebPConfig) {
@@ -141,6 +146,13 @@ func (opts *EncodeOptions) from(c *C.WebPConfig) {
 	opts.Exact = int(c.exact) == 1
 	opts.UseDeltaPalette = int(c.use_delta_palette) // reserved for future lossless feature
 	opts.UseSharpYUV = int(c.use_sharp_yuv) == 1
+	if SupportsQRange() {
+		var qmin, qmax C.int
+		C.GoWebPConfigGetQRange(c, &qmin, &qmax)
+		opts.QMin, opts.QMax = int(qmin), int(qmax)
+	} else {
+		opts.QMin, opts.QMax = 0, 100
+	}
 }
 
 func (opts *EncodeOptions) assign(c *C.WebPConfig) {
@@ -171,6 +183,24 @@ func (opts *EncodeOptions) assign(c *C.WebPConfig) {
 	c.exact = bool2CInt(opts.Exact)
 	c.use_delta_palette = C.int(opts.UseDeltaPalette)
 	c.use_sharp_yuv = bool2CInt(opts.UseSharpYUV)
+	if SupportsQRange() {
+		qmin, qmax := opts.qRange()
+		C.GoWebPConfigSetQRange(c, C.int(qmin), C.int(qmax))
+	}
+}
+
+// SupportsQRange report whether EncodeOptions.QMin and QMax are passed to libwebp,
+// that require building against and linking libwebp 1.2 or newer.
+func SupportsQRange() bool {
+	return C.GO_WEBP_HAS_QRANGE != 0 && EncoderVersion() >= 0x010200
+}
+
+func (opts *EncodeOptions) qRange() (qmin, qmax int) {
+	qmin, qmax = opts.QMin, opts.QMax
+	if qmax == 0 {
+		qmax = 100
+	}
+	return
 }
 
 func NewEncOptions() (*EncodeOptions, error) {
@@ -33,6 +33,7 @@ import (
 	"image"
 	"image/color"
 	"io"
+	"time"
 	"unsafe"
 )
 
@@ -40,16 +41,92 @@ func EncoderVersion() Version {
 	return Version(C.WebPGetEncoderVersion())
 }
 
+// Encode stream the encoded data to w as libwebp produce it, w may have
+// received partial data if an error is returned.
 func Encode(w io.Writer, img image.Image, opts *EncodeOptions) error {
-	data, err := encode(img, opts)
+	_, err := encodeTo(w, img, opts, nil)
+	return err
+}
+
+// EncodeWithStats is like Encode but also collect the encoder statistics
+func EncodeWithStats(w io.Writer, img image.Image, opts *EncodeOptions) (*EncodeStats, error) {
+	return collectStats(func(stats *C.WebPAuxStats) (*MacroblockInfo, error) {
+		return encodeTo(w, img, opts, stats)
+	})
+}
+
+// collectStats call run with C allocated stats and convert them
+func collectStats(run func(stats *C.WebPAuxStats) (*MacroblockInfo, error)) (*EncodeStats, error) {
+	stats := (*C.WebPAuxStats)(C.calloc(1, C.sizeof_WebPAuxStats))
+	if stats == nil {
+		return nil, VP8EncErrorOutOfMemory
+	}
+	defer C.free(unsafe.Pointer(stats))
+
+	start := time.Now()
+	info, err := run(stats)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer data.release()
-	if _, err = w.Write(data); err != nil {
-		return err
+	s := newEncodeStats(stats)
+	s.Duration = time.Since(start)
+	s.MacroblockInfo = info
+	return s, nil
+}
+
+// encodeTo encode img to w, the per macroblock info selected by opts.ExtraInfo
+// is captured only if stats is not nil.
+func encodeTo(w io.Writer, img image.Image, opts *EncodeOptions, stats *C.WebPAuxStats) (*MacroblockInfo, error) {
+	var config C.WebPConfig
+	var pic C.WebPPicture
+	defer C.WebPPictureFree(&pic)
+
+	holder, err := setupEncode(&pic, &config, img, opts)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return encodePicTo(w, &pic, &config, holder, opts, stats)
+}
+
+// encodePicTo encode a picture which is already set up, holder may be nil if pic own its memory
+func encodePicTo(w io.Writer, pic *C.WebPPicture, config *C.WebPConfig, holder *C.PixMemHolder, opts *EncodeOptions, stats *C.WebPAuxStats) (*MacroblockInfo, error) {
+	pic.stats = stats
+
+	var info *MacroblockInfo
+	if stats != nil && opts.ExtraInfo != ExtraInfoNone && !opts.Lossless {
+		mbw, mbh := (int(pic.width)+15)/16, (int(pic.height)+15)/16
+		extra := (*C.uint8_t)(C.calloc(C.size_t(mbw*mbh), 1))
+		if extra == nil {
+			return nil, VP8EncErrorOutOfMemory
+		}
+		defer C.free(unsafe.Pointer(extra))
+		pic.extra_info_type = C.int(opts.ExtraInfo)
+		pic.extra_info = extra
+		info = &MacroblockInfo{Type: opts.ExtraInfo, Width: mbw, Height: mbh}
+	}
+
+	handle, pw := registerPicWriter(w)
+	defer releasePicWriter(handle)
+
+	var ok C.int
+	if holder == nil {
+		ok = C.GoWebPEncodeToWriter(pic, config, C.uintptr_t(handle))
+	} else {
+		ok = C.GoWebPEncodeToWriterUseGoMem(pic, config, C.uintptr_t(handle), *holder)
+	}
+
+	if int(ok) == 0 {
+		if pw.err != nil {
+			return nil, &EncodeWriteError{Err: pw.err}
+		}
+		return nil, VP8EncodeError(pic.error_code)
+	}
+
+	if info != nil {
+		info.Values = C.GoBytes(unsafe.Pointer(pic.extra_info), C.int(info.Width*info.Height))
+		pic.extra_info = nil
+	}
+	return info, nil
 }
 
 func EncodeSlice(img image.Image, opts *EncodeOptions) ([]byte, error) {
@@ -64,15 +141,9 @@ func encode(img image.Image, opts *EncodeOptions) (unsafeBytes, error) {
 
 	var config C.WebPConfig
 	var pic C.WebPPicture
-
-	opts.assign(&config)
-	if !validateEncodeConfig(&config) {
-		return nil, VP8EncErrorInvalidConfiguration
-	}
-
 	defer C.WebPPictureFree(&pic)
 
-	holder, err := webpPictureImport(&pic, img, opts)
+	holder, err := setupEncode(&pic, &config, img, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +163,15 @@ func encode(img image.Image, opts *EncodeOptions) (unsafeBytes, error) {
 	return wrapUnsafeBytes(out, outSize), nil
 }
 
+// setupEncode validate opts into config and import img into pic
+func setupEncode(pic *C.WebPPicture, config *C.WebPConfig, img image.Image, opts *EncodeOptions) (*C.PixMemHolder, error) {
+	opts.assign(config)
+	if !validateEncodeConfig(config) {
+		return nil, VP8EncErrorInvalidConfiguration
+	}
+	return webpPictureImport(pic, img, opts)
+}
+
 func webpPictureImport(pic *C.WebPPicture, img image.Image, opts *EncodeOptions) (*C.PixMemHolder, error) {
 	var holder *C.PixMemHolder
 	var err error
@@ -126,9 +206,17 @@ func webpPictureImport(pic *C.WebPPicture, img image.Image, opts *EncodeOptions)
 	case *image.Gray16:
 		holder, err = webpPictureImportPix(pic, m.Pix, width, height, 2, 0, 0, 0, -1)
 	case *YCbCr:
-		holder, err = webpPictureImportYUVA(pic, &m.YCbCr, nil, -1)
+		if m.SubsampleRatio != image.YCbCrSubsampleRatio420 { //e.g. TypeYUV444, libwebp only take 4:2:0
+			holder, err = webpPictureImportCommon(pic, img)
+		} else {
+			holder, err = webpPictureImportYUVA(pic, &m.YCbCr, nil, -1)
+		}
 	case *NYCbCrA:
-		holder, err = webpPictureImportYUVA(pic, &m.YCbCr, m.A, m.AStride)
+		if m.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+			holder, err = webpPictureImportCommon(pic, img)
+		} else {
+			holder, err = webpPictureImportYUVA(pic, &m.YCbCr, m.A, m.AStride)
+		}
 	case *image.YCbCr, *image.NYCbCrA:
 		//image.YCbCr is JFIF standard, but webp yuv is ITU-R BT.601 standard, so we need convert to rgb
 		holder, err = webpPictureImportCommon(pic, img)
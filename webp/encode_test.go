@@ -0,0 +1,41 @@
+package webp
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestEncodeYUV444(t *testing.T) {
+	const w, h = 32, 32
+	data := testWebP(t, w, h, false)
+	opts := NewDecOptions()
+	opts.ImageType = TypeYUV444
+	src, err := DecodeSlice(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the full resolution chroma must not be imported as 4:2:0
+	enc, err := NewEncOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.Lossless = true
+	out, err := EncodeSlice(src, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ImageType = TypeNRGBA
+	got, err := DecodeSlice(out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			want := color.NRGBAModel.Convert(src.At(x, y))
+			if c := got.At(x, y); c != want {
+				t.Fatalf("%T: pixel (%d, %d) is %v, want %v", src, x, y, c, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package icc parse RGB matrix/TRC ICC profiles and convert colors between them.
+package icc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	ErrInvalid     = errors.New("icc: invalid profile")
+	ErrUnsupported = errors.New("icc: unsupported profile, only RGB matrix/TRC profiles are supported")
+)
+
+// Curve is a tone reproduction curve which map encoded value to linear light, both in [0, 1]
+type Curve interface {
+	Eval(x float64) float64
+}
+
+// GammaCurve is Y = X^gamma
+type GammaCurve float64
+
+func (c GammaCurve) Eval(x float64) float64 {
+	return math.Pow(clamp01(x), float64(c))
+}
+
+// ParametricCurve is the ICC parametricCurveType, Params hold g, a, b, c, d, e, f
+type ParametricCurve struct {
+	Type   int
+	Params [7]float64
+}
+
+func (c ParametricCurve) Eval(x float64) float64 {
+	g, a, b, cc, d, e, f := c.Params[0], c.Params[1], c.Params[2], c.Params[3], c.Params[4], c.Params[5], c.Params[6]
+	var y float64
+	switch c.Type {
+	case 0:
+		y = math.Pow(x, g)
+	case 1:
+		if x >= -b/a {
+			y = math.Pow(a*x+b, g)
+		}
+	case 2:
+		y = cc
+		if x >= -b/a {
+			y += math.Pow(a*x+b, g)
+		}
+	case 3:
+		if x >= d {
+			y = math.Pow(a*x+b, g)
+		} else {
+			y = cc * x
+		}
+	case 4:
+		if x >= d {
+			y = math.Pow(a*x+b, g) + e
+		} else {
+			y = cc*x + f
+		}
+	}
+	return clamp01(y)
+}
+
+// SampledCurve is a curve sampled at evenly spaced points, interpolated linearly
+type SampledCurve []float64
+
+func (c SampledCurve) Eval(x float64) float64 {
+	x = clamp01(x)
+	if len(c) == 0 {
+		return x
+	}
+	if len(c) == 1 {
+		return c[0]
+	}
+	p := x * float64(len(c)-1)
+	i := int(p)
+	if i >= len(c)-1 {
+		return c[len(c)-1]
+	}
+	t := p - float64(i)
+	return c[i]*(1-t) + c[i+1]*t
+}
+
+// Profile is a RGB matrix/TRC profile
+type Profile struct {
+	// columns are the red, green and blue colorants in PCS XYZ (D50)
+	Matrix [3][3]float64
+	// red, green and blue tone reproduction curves
+	TRC [3]Curve
+}
+
+// SRGB is the sRGB IEC61966-2.1 profile
+var SRGB = &Profile{
+	Matrix: [3][3]float64{
+		{0.4360747, 0.3850649, 0.1430804},
+		{0.2225045, 0.7168786, 0.0606169},
+		{0.0139322, 0.0971045, 0.7141733},
+	},
+	TRC: [3]Curve{srgbTRC, srgbTRC, srgbTRC},
+}
+
+var srgbTRC = ParametricCurve{Type: 3, Params: [7]float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045}}
+
+const headerSize = 128
+
+var (
+	sigRGB  = sig("RGB ")
+	sigXYZ  = sig("XYZ ")
+	sigCurv = sig("curv")
+	sigPara = sig("para")
+	sigAcsp = sig("acsp")
+
+	colorantTags = [3]uint32{sig("rXYZ"), sig("gXYZ"), sig("bXYZ")}
+	trcTags      = [3]uint32{sig("rTRC"), sig("gTRC"), sig("bTRC")}
+)
+
+// Parse parse a ICC profile, e.g. the payload of ICCP chunk
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < headerSize+4 || be32(data[36:]) != sigAcsp {
+		return nil, ErrInvalid
+	}
+	if be32(data[16:]) != sigRGB || be32(data[20:]) != sigXYZ {
+		return nil, ErrUnsupported
+	}
+
+	tags := make(map[uint32][]byte)
+	n := int(be32(data[headerSize:]))
+	for i, p := 0, headerSize+4; i < n; i, p = i+1, p+12 {
+		if p+12 > len(data) {
+			return nil, ErrInvalid
+		}
+		off, size := int(be32(data[p+4:])), int(be32(data[p+8:]))
+		if off < 0 || size < 0 || off+size > len(data) || off+size < off {
+			return nil, ErrInvalid
+		}
+		tags[be32(data[p:])] = data[off : off+size]
+	}
+
+	p := new(Profile)
+	for i := 0; i < 3; i++ {
+		xyz, ok := tags[colorantTags[i]]
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		if len(xyz) < 20 || be32(xyz) != sigXYZ {
+			return nil, ErrInvalid
+		}
+		for j := 0; j < 3; j++ {
+			p.Matrix[j][i] = s15Fixed16(xyz[8+4*j:])
+		}
+
+		trc, ok := tags[trcTags[i]]
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		c, err := parseCurve(trc)
+		if err != nil {
+			return nil, err
+		}
+		p.TRC[i] = c
+	}
+
+	if _, ok := invert(p.Matrix); !ok {
+		return nil, ErrInvalid
+	}
+	return p, nil
+}
+
+func parseCurve(b []byte) (Curve, error) {
+	if len(b) < 12 {
+		return nil, ErrInvalid
+	}
+	switch be32(b) {
+	case sigCurv:
+		n := int(be32(b[8:]))
+		if len(b) < 12+2*n {
+			return nil, ErrInvalid
+		}
+		switch n {
+		case 0:
+			return GammaCurve(1), nil
+		case 1:
+			return GammaCurve(float64(binary.BigEndian.Uint16(b[12:])) / 256), nil
+		}
+		c := make(SampledCurve, n)
+		for i := range c {
+			c[i] = float64(binary.BigEndian.Uint16(b[12+2*i:])) / 0xffff
+		}
+		return c, nil
+	case sigPara:
+		typ := int(binary.BigEndian.Uint16(b[8:]))
+		count := [...]int{1, 3, 4, 5, 7}
+		if typ >= len(count) {
+			return nil, ErrUnsupported
+		}
+		if len(b) < 12+4*count[typ] {
+			return nil, ErrInvalid
+		}
+		c := ParametricCurve{Type: typ}
+		for i := 0; i < count[typ]; i++ {
+			c.Params[i] = s15Fixed16(b[12+4*i:])
+		}
+		if typ > 0 && c.Params[1] == 0 {
+			return nil, ErrInvalid
+		}
+		return c, nil
+	}
+	return nil, ErrUnsupported
+}
+
+func sig(s string) uint32 {
+	return be32([]byte(s))
+}
+
+func be32(b []byte) uint32 {
+	return binary.BigEndian.Uint32(b)
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(be32(b))) / 65536
+}
+
+func clamp01(x float64) float64 {
+	if x < 0 {
+		return 0
+	} else if x > 1 {
+		return 1
+	}
+	return x
+}
+
+func invert(m [3][3]float64) (inv [3][3]float64, ok bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if math.Abs(det) < 1e-12 {
+		return inv, false
+	}
+	inv[0][0] = (m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det
+	inv[0][1] = (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det
+	inv[0][2] = (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det
+	inv[1][0] = (m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det
+	inv[1][1] = (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det
+	inv[1][2] = (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det
+	inv[2][0] = (m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det
+	inv[2][1] = (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det
+	inv[2][2] = (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det
+	return inv, true
+}
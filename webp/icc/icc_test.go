@@ -0,0 +1,295 @@
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+type testTag struct {
+	sig  string
+	data []byte
+}
+
+// testProfile build an ICC profile with the given data colour space and tags
+func testProfile(space string, tags ...testTag) []byte {
+	b := make([]byte, headerSize+4+12*len(tags))
+	copy(b[16:], space)
+	copy(b[20:], "XYZ ")
+	copy(b[36:], "acsp")
+	binary.BigEndian.PutUint32(b[headerSize:], uint32(len(tags)))
+	for i, t := range tags {
+		p := headerSize + 4 + 12*i
+		copy(b[p:], t.sig)
+		binary.BigEndian.PutUint32(b[p+4:], uint32(len(b)))
+		binary.BigEndian.PutUint32(b[p+8:], uint32(len(t.data)))
+		b = append(b, t.data...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+func s15(v float64) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(int32(math.Round(v*65536))))
+	return b
+}
+
+func xyzTag(x, y, z float64) []byte {
+	b := []byte("XYZ \x00\x00\x00\x00")
+	b = append(b, s15(x)...)
+	b = append(b, s15(y)...)
+	return append(b, s15(z)...)
+}
+
+func paraTag(typ int, params ...float64) []byte {
+	b := []byte("para\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(b[8:], uint16(typ))
+	for _, p := range params {
+		b = append(b, s15(p)...)
+	}
+	return b
+}
+
+func curvTag(values ...uint16) []byte {
+	b := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint32(b[8:], uint32(len(values)))
+	for _, v := range values {
+		b = append(b, byte(v>>8), byte(v))
+	}
+	return b
+}
+
+var srgbPara = paraTag(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+
+func matrixProfile(m [3][3]float64, trc ...[]byte) []byte {
+	tags := []testTag{
+		{"rXYZ", xyzTag(m[0][0], m[1][0], m[2][0])},
+		{"gXYZ", xyzTag(m[0][1], m[1][1], m[2][1])},
+		{"bXYZ", xyzTag(m[0][2], m[1][2], m[2][2])},
+	}
+	for i, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, testTag{sig, trc[i%len(trc)]})
+	}
+	return testProfile("RGB ", tags...)
+}
+
+var (
+	srgbData = matrixProfile(SRGB.Matrix, srgbPara)
+	// Display P3, colorants adapted to D50 as in the profile shipped by Apple
+	displayP3Data = matrixProfile([3][3]float64{
+		{0.515121, 0.291977, 0.157104},
+		{0.241196, 0.692245, 0.066574},
+		{-0.001053, 0.041885, 0.784073},
+	}, srgbPara)
+)
+
+func TestParse(t *testing.T) {
+	valid := srgbData
+	badOffset := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(badOffset[headerSize+4+4:], uint32(len(valid)))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"srgb", srgbData, nil},
+		{"display p3", displayP3Data, nil},
+		{"gamma curv", matrixProfile(SRGB.Matrix, curvTag(0x0233)), nil},
+		{"sampled curv", matrixProfile(SRGB.Matrix, curvTag(0, 0x4000, 0xffff)), nil},
+		{"identity curv", matrixProfile(SRGB.Matrix, curvTag()), nil},
+		{"too short", valid[:headerSize], ErrInvalid},
+		{"no signature", append(make([]byte, 36), valid[36+4:]...), ErrInvalid},
+		{"cmyk", testProfile("CMYK"), ErrUnsupported},
+		{"lut based", testProfile("RGB ", testTag{"A2B0", make([]byte, 32)}), ErrUnsupported},
+		{"missing trc", testProfile("RGB ", testTag{"rXYZ", xyzTag(1, 0, 0)}, testTag{"gXYZ", xyzTag(0, 1, 0)}, testTag{"bXYZ", xyzTag(0, 0, 1)}), ErrUnsupported},
+		{"tag out of range", badOffset, ErrInvalid},
+		{"singular matrix", matrixProfile([3][3]float64{{1, 1, 0}, {1, 1, 0}, {0, 0, 1}}, srgbPara), ErrInvalid},
+		{"para type 5", matrixProfile(SRGB.Matrix, paraTag(5, 1)), ErrUnsupported},
+		{"short curv", matrixProfile(SRGB.Matrix, curvTag(1, 2, 3)[:14]), ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.data); err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestParseSRGB(t *testing.T) {
+	p, err := Parse(srgbData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range p.Matrix {
+		for j := range p.Matrix[i] {
+			if d := math.Abs(p.Matrix[i][j] - SRGB.Matrix[i][j]); d > 1e-4 {
+				t.Errorf("Matrix[%d][%d] = %v, want %v", i, j, p.Matrix[i][j], SRGB.Matrix[i][j])
+			}
+		}
+	}
+	for x := 0.0; x <= 1; x += 1.0 / 64 {
+		if got, want := p.TRC[0].Eval(x), SRGB.TRC[0].Eval(x); math.Abs(got-want) > 1e-4 {
+			t.Errorf("TRC(%v) = %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestCurve(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Curve
+		x, y float64
+	}{
+		{"gamma", GammaCurve(2), 0.5, 0.25},
+		{"gamma clamp", GammaCurve(2), 1.5, 1},
+		{"para 0", ParametricCurve{Type: 0, Params: [7]float64{2}}, 0.5, 0.25},
+		{"para 1 below", ParametricCurve{Type: 1, Params: [7]float64{1, 1, -0.5}}, 0.25, 0},
+		{"para 1 above", ParametricCurve{Type: 1, Params: [7]float64{1, 1, -0.5}}, 0.75, 0.25},
+		{"para 2", ParametricCurve{Type: 2, Params: [7]float64{1, 1, -0.5, 0.1}}, 0.25, 0.1},
+		{"srgb linear part", SRGB.TRC[0], 0.02, 0.02 / 12.92},
+		{"srgb power part", SRGB.TRC[0], 0.5, 0.21404114},
+		{"para 4", ParametricCurve{Type: 4, Params: [7]float64{1, 1, 0, 0.5, 0.5, 0.1, 0.2}}, 0.25, 0.325},
+		{"sampled", SampledCurve{0, 0.5, 1}, 0.25, 0.25},
+		{"sampled end", SampledCurve{0, 0.2, 0.8}, 1, 0.8},
+		{"sampled single", SampledCurve{0.3}, 0.9, 0.3},
+		{"sampled empty", SampledCurve{}, 0.4, 0.4},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Eval(tt.x); math.Abs(got-tt.y) > 1e-6 {
+			t.Errorf("%s: Eval(%v) = %v, want %v", tt.name, tt.x, got, tt.y)
+		}
+	}
+}
+
+func abs8(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func TestTransform(t *testing.T) {
+	p3, err := Parse(displayP3Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srgbToSRGB, err := NewTransform(SRGB, SRGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srgbToP3, err := NewTransform(SRGB, p3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p3ToSRGB, err := NewTransform(p3, SRGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		t       *Transform
+		in, out [3]uint8
+	}{
+		{"srgb identity", srgbToSRGB, [3]uint8{12, 128, 250}, [3]uint8{12, 128, 250}},
+		{"srgb red to p3", srgbToP3, [3]uint8{255, 0, 0}, [3]uint8{234, 51, 35}},
+		{"srgb green to p3", srgbToP3, [3]uint8{0, 255, 0}, [3]uint8{117, 251, 76}},
+		{"srgb blue to p3", srgbToP3, [3]uint8{0, 0, 255}, [3]uint8{0, 0, 245}},
+		{"p3 white to srgb", p3ToSRGB, [3]uint8{255, 255, 255}, [3]uint8{255, 255, 255}},
+		{"p3 red clipped to srgb", p3ToSRGB, [3]uint8{255, 0, 0}, [3]uint8{255, 0, 0}},
+	}
+	for _, tt := range tests {
+		r, g, b := tt.t.Convert(tt.in[0], tt.in[1], tt.in[2])
+		if abs8(r, tt.out[0]) > 1 || abs8(g, tt.out[1]) > 1 || abs8(b, tt.out[2]) > 1 {
+			t.Errorf("%s: Convert(%v) = [%d %d %d], want %v", tt.name, tt.in, r, g, b, tt.out)
+		}
+	}
+
+	// gray levels and mid tone colors survive an sRGB -> P3 -> sRGB round trip, saturated
+	// colors near black don't, a channel close to 0 is lost in the 8 bit P3 rounding
+	colors := make([][3]uint8, 0, 512)
+	for v := 0; v < 256; v++ {
+		colors = append(colors, [3]uint8{uint8(v), uint8(v), uint8(v)})
+		if v >= 64 && v < 192 {
+			colors = append(colors, [3]uint8{uint8(v), uint8(255 - v), 128})
+		}
+	}
+	for _, c := range colors {
+		pr, pg, pb := srgbToP3.Convert(c[0], c[1], c[2])
+		r, g, b := p3ToSRGB.Convert(pr, pg, pb)
+		if abs8(r, c[0]) > 2 || abs8(g, c[1]) > 2 || abs8(b, c[2]) > 2 {
+			t.Errorf("round trip %v = [%d %d %d]", c, r, g, b)
+		}
+	}
+}
+
+func TestTransformConvertPix(t *testing.T) {
+	tr, err := NewTransform(SRGB, SRGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2x2 RGBA with a 12 bytes stride, padding and alpha must be left alone
+	pix := []uint8{
+		10, 20, 30, 40, 50, 60, 70, 80, 0xee, 0xee, 0xee, 0xee,
+		90, 100, 110, 120, 130, 140, 150, 160, 0xee, 0xee, 0xee, 0xee,
+	}
+	want := append([]uint8(nil), pix...)
+	tr.ConvertPix(pix, 12, 2, 2, 4)
+	for i := range pix {
+		if abs8(pix[i], want[i]) > 1 {
+			t.Errorf("pix[%d] = %d, want %d", i, pix[i], want[i])
+		}
+	}
+
+	if _, err := NewTransform(SRGB, &Profile{TRC: SRGB.TRC}); err != ErrInvalid {
+		t.Errorf("singular destination: err = %v, want %v", err, ErrInvalid)
+	}
+}
+
+func abs16(a, b uint16) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func TestTransform16(t *testing.T) {
+	p3, err := Parse(displayP3Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := NewTransform(SRGB, SRGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toP3, err := NewTransform(SRGB, p3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 16 bit results agree with the 8 bit ones, and sRGB to sRGB keep the value within one 8 bit level
+	for v := 0; v < 256; v += 3 {
+		c := [3]uint8{uint8(v), uint8(255 - v), uint8(v / 2)}
+		for _, tr := range []*Transform{identity, toP3} {
+			r8, g8, b8 := tr.Convert(c[0], c[1], c[2])
+			r, g, b := tr.Convert16(uint16(c[0])*0x101, uint16(c[1])*0x101, uint16(c[2])*0x101)
+			if abs8(uint8(r>>8), r8) > 1 || abs8(uint8(g>>8), g8) > 1 || abs8(uint8(b>>8), b8) > 1 {
+				t.Errorf("Convert16(%v) = [%d %d %d], 8 bit [%d %d %d]", c, r>>8, g>>8, b>>8, r8, g8, b8)
+			}
+		}
+	}
+	for v := 0; v <= 0xffff; v += 251 {
+		in := uint16(v)
+		if r, g, b := identity.Convert16(in, in, in); abs16(r, in) > 0x101 || abs16(g, in) > 0x101 || abs16(b, in) > 0x101 {
+			t.Errorf("identity Convert16(%d) = [%d %d %d]", in, r, g, b)
+		}
+	}
+
+	// ConvertPix16 leave alpha and padding alone
+	pix := []uint8{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0xee, 0xee}
+	identity.ConvertPix16(pix, 10, 1, 1, 8)
+	if pix[6] != 0xde || pix[7] != 0xf0 || pix[8] != 0xee || pix[9] != 0xee {
+		t.Errorf("ConvertPix16 changed alpha or padding: %x", pix)
+	}
+}
@@ -0,0 +1,140 @@
+package icc
+
+// resolution of the linear light to encoded value lookup table
+const encLUTSize = 4096
+
+// resolution of the encoded value to linear light lookup table of 16 bit samples
+const lin16LUTSize = 4096
+
+// Transform convert 8 or 16 bit RGB from a source profile to a destination profile
+type Transform struct {
+	lin   [3][256]float32
+	lin16 [3][lin16LUTSize + 1]float32
+	m     [3][3]float32
+	enc   [3][encLUTSize]uint8
+	enc16 [3][encLUTSize]uint16
+}
+
+// NewTransform build the lookup tables to convert colors from src to dst
+func NewTransform(src, dst *Profile) (*Transform, error) {
+	inv, ok := invert(dst.Matrix)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	t := new(Transform)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			var v float64
+			for k := 0; k < 3; k++ {
+				v += inv[i][k] * src.Matrix[k][j]
+			}
+			t.m[i][j] = float32(v)
+		}
+		for v := range t.lin[i] {
+			t.lin[i][v] = float32(src.TRC[i].Eval(float64(v) / 255))
+		}
+		for v := range t.lin16[i] {
+			t.lin16[i][v] = float32(src.TRC[i].Eval(float64(v) / lin16LUTSize))
+		}
+		buildEncLUT(&t.enc[i], &t.enc16[i], dst.TRC[i])
+	}
+	return t, nil
+}
+
+// buildEncLUT invert a monotonic curve by bisection
+func buildEncLUT(lut *[encLUTSize]uint8, lut16 *[encLUTSize]uint16, c Curve) {
+	increasing := c.Eval(1) >= c.Eval(0)
+	for i := range lut {
+		y := float64(i) / (encLUTSize - 1)
+		lo, hi := 0.0, 1.0
+		for n := 0; n < 24; n++ {
+			mid := (lo + hi) / 2
+			if (c.Eval(mid) < y) == increasing {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		lut[i] = uint8((lo+hi)/2*255 + 0.5)
+		lut16[i] = uint16((lo+hi)/2*0xffff + 0.5)
+	}
+}
+
+// Convert convert a non-premultiplied color
+func (t *Transform) Convert(r, g, b uint8) (uint8, uint8, uint8) {
+	lr, lg, lb := t.lin[0][r], t.lin[1][g], t.lin[2][b]
+	return t.encode(0, t.m[0][0]*lr+t.m[0][1]*lg+t.m[0][2]*lb),
+		t.encode(1, t.m[1][0]*lr+t.m[1][1]*lg+t.m[1][2]*lb),
+		t.encode(2, t.m[2][0]*lr+t.m[2][1]*lg+t.m[2][2]*lb)
+}
+
+func (t *Transform) encode(ch int, v float32) uint8 {
+	if v <= 0 {
+		return t.enc[ch][0]
+	} else if v >= 1 {
+		return t.enc[ch][encLUTSize-1]
+	}
+	return t.enc[ch][int(v*(encLUTSize-1)+0.5)]
+}
+
+// ConvertPix convert interleaved pixels in place, bpp is bytes per pixel and
+// the first 3 bytes of a pixel must be non-premultiplied R, G, B.
+func (t *Transform) ConvertPix(pix []uint8, stride, width, height, bpp int) {
+	for y := 0; y < height; y++ {
+		row := pix[y*stride : y*stride+width*bpp]
+		for i := 0; i < len(row); i += bpp {
+			row[i], row[i+1], row[i+2] = t.Convert(row[i], row[i+1], row[i+2])
+		}
+	}
+}
+
+// Convert16 convert a non-premultiplied 16 bit color
+func (t *Transform) Convert16(r, g, b uint16) (uint16, uint16, uint16) {
+	lr, lg, lb := t.linear16(0, r), t.linear16(1, g), t.linear16(2, b)
+	return t.encode16(0, t.m[0][0]*lr+t.m[0][1]*lg+t.m[0][2]*lb),
+		t.encode16(1, t.m[1][0]*lr+t.m[1][1]*lg+t.m[1][2]*lb),
+		t.encode16(2, t.m[2][0]*lr+t.m[2][1]*lg+t.m[2][2]*lb)
+}
+
+func (t *Transform) linear16(ch int, v uint16) float32 {
+	p := float32(v) * lin16LUTSize / 0xffff
+	i := int(p)
+	if i >= lin16LUTSize {
+		return t.lin16[ch][lin16LUTSize]
+	}
+	f := p - float32(i)
+	return t.lin16[ch][i]*(1-f) + t.lin16[ch][i+1]*f
+}
+
+func (t *Transform) encode16(ch int, v float32) uint16 {
+	if v <= 0 {
+		return t.enc16[ch][0]
+	} else if v >= 1 {
+		return t.enc16[ch][encLUTSize-1]
+	}
+	p := v * (encLUTSize - 1)
+	i := int(p)
+	if i >= encLUTSize-1 {
+		return t.enc16[ch][encLUTSize-1]
+	}
+	f := p - float32(i)
+	return uint16(float32(t.enc16[ch][i])*(1-f) + float32(t.enc16[ch][i+1])*f + 0.5)
+}
+
+// ConvertPix16 convert interleaved big endian 16 bit pixels in place like image.NRGBA64,
+// bpp is bytes per pixel and the first 6 bytes of a pixel must be non-premultiplied R, G, B.
+func (t *Transform) ConvertPix16(pix []uint8, stride, width, height, bpp int) {
+	for y := 0; y < height; y++ {
+		row := pix[y*stride : y*stride+width*bpp]
+		for i := 0; i < len(row); i += bpp {
+			r, g, b := t.Convert16(
+				uint16(row[i])<<8|uint16(row[i+1]),
+				uint16(row[i+2])<<8|uint16(row[i+3]),
+				uint16(row[i+4])<<8|uint16(row[i+5]))
+			row[i], row[i+1] = uint8(r>>8), uint8(r)
+			row[i+2], row[i+3] = uint8(g>>8), uint8(g)
+			row[i+4], row[i+5] = uint8(b>>8), uint8(b)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package webp
+
+/*
+#include <webp/mux_types.h>
+*/
+import "C"
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+)
+
+type FeatureFlags uint32
+
+const (
+	AnimationFlag FeatureFlags = C.ANIMATION_FLAG
+	XMPFlag       FeatureFlags = C.XMP_FLAG
+	EXIFFlag      FeatureFlags = C.EXIF_FLAG
+	AlphaFlag     FeatureFlags = C.ALPHA_FLAG
+	ICCPFlag      FeatureFlags = C.ICCP_FLAG
+)
+
+var (
+	riffFourCC = FourCC{'R', 'I', 'F', 'F'}
+	webpFourCC = FourCC{'W', 'E', 'B', 'P'}
+	vp8xFourCC = FourCC{'V', 'P', '8', 'X'}
+	vp8FourCC  = FourCC{'V', 'P', '8', ' '}
+	vp8lFourCC = FourCC{'V', 'P', '8', 'L'}
+	alphFourCC = FourCC{'A', 'L', 'P', 'H'}
+	animFourCC = FourCC{'A', 'N', 'I', 'M'}
+	anmfFourCC = FourCC{'A', 'N', 'M', 'F'}
+)
+
+type ContainerFeatures struct {
+	// canvas size, for simple format it is the bitstream size
+	Width, Height int
+	// true if file use the extended format (has VP8X chunk)
+	Extended bool
+	// VP8X flags, zero for simple format
+	Flags FeatureFlags
+	// FormatMixed if frames of animation use different format
+	Format       BitStreamFormat
+	HasAlpha     bool
+	HasAnimation bool
+	FrameCount   int
+	// 0 means infinite loop, only meaningful for animation
+	LoopCount int
+	// size of each present metadata chunk (ICCP, EXIF, XMP)
+	Metadata map[FourCC]int
+}
+
+// Inspect parse the RIFF container from r, chunk payloads which are not needed
+// are skipped (by Seek if r is an io.Seeker), so only chunk headers and a few
+// bytes of the image headers are read.
+func Inspect(r io.Reader) (*ContainerFeatures, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if !fourCCAt(hdr[:], 0, riffFourCC) || !fourCCAt(hdr[:], 8, webpFourCC) {
+		return nil, VP8StatusBitstreamError.error("Inspect: not a RIFF WebP file, ")
+	}
+
+	f := &ContainerFeatures{Metadata: make(map[FourCC]int)}
+	var lossy, lossless bool
+	var hasALPH bool
+	remain := int64(binary.LittleEndian.Uint32(hdr[4:])) - 4
+	for remain >= 8 {
+		fourcc, size, err := readChunkHeader(r)
+		if err == io.EOF {
+			break // tolerate truncated RIFF size
+		} else if err != nil {
+			return nil, err
+		}
+		padded := int64(size) + int64(size&1)
+		remain -= 8 + padded
+
+		var read int
+		switch fourcc {
+		case vp8xFourCC:
+			var b [10]byte
+			if read, err = readPayload(r, b[:], size); err != nil {
+				return nil, err
+			}
+			f.Extended = true
+			f.Flags = FeatureFlags(b[0])
+			f.Width = int(getLE24(b[4:])) + 1
+			f.Height = int(getLE24(b[7:])) + 1
+		case animFourCC:
+			var b [6]byte
+			if read, err = readPayload(r, b[:], size); err != nil {
+				return nil, err
+			}
+			f.LoopCount = int(binary.LittleEndian.Uint16(b[4:]))
+		case anmfFourCC:
+			f.FrameCount++
+			var frameLossless bool
+			if frameLossless, read, err = inspectFrame(r, size); err != nil {
+				return nil, err
+			}
+			lossless = lossless || frameLossless
+			lossy = lossy || !frameLossless
+		case vp8FourCC, vp8lFourCC:
+			f.FrameCount++
+			var b [10]byte
+			if read, err = readPayload(r, b[:], size); err != nil {
+				return nil, err
+			}
+			w, h, alpha, ok := parseBitstreamHeader(fourcc, b[:read])
+			if !ok {
+				return nil, VP8StatusBitstreamError.error("Inspect: invalid " + string(fourcc[:]) + " header, ")
+			}
+			if !f.Extended {
+				f.Width, f.Height = w, h
+			}
+			hasALPH = hasALPH || alpha
+			lossless = lossless || fourcc == vp8lFourCC
+			lossy = lossy || fourcc == vp8FourCC
+		case alphFourCC:
+			hasALPH = true
+		case ICCP, EXIF, XMP:
+			f.Metadata[fourcc] = int(size)
+		}
+
+		if err = skip(r, padded-int64(read)); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.FrameCount == 0 {
+		return nil, VP8StatusNotEnoughData.error("Inspect: no image data found, ")
+	}
+
+	f.HasAnimation = f.Flags&AnimationFlag != 0
+	f.HasAlpha = f.Flags&AlphaFlag != 0 || hasALPH
+	switch {
+	case lossy && lossless:
+		f.Format = FormatMixed
+	case lossless:
+		f.Format = FormatLossless
+	default:
+		f.Format = FormatLossy
+	}
+	return f, nil
+}
+
+// inspectFrame walk the sub chunks of an ANMF chunk and report whether the frame is lossless
+func inspectFrame(r io.Reader, size uint32) (lossless bool, read int, err error) {
+	const anmfHeaderSize = 16
+	if size < anmfHeaderSize {
+		err = VP8StatusBitstreamError.error("Inspect: invalid ANMF chunk, ")
+		return
+	}
+	if err = skip(r, anmfHeaderSize); err != nil {
+		return
+	}
+	read = anmfHeaderSize
+	for int(size)-read >= 8 {
+		var fourcc FourCC
+		var sub uint32
+		if fourcc, sub, err = readChunkHeader(r); err != nil {
+			return
+		}
+		read += 8
+		if fourcc == vp8lFourCC {
+			lossless = true
+		}
+		padded := int(sub) + int(sub&1)
+		if padded > int(size)-read {
+			padded = int(size) - read
+		}
+		if err = skip(r, int64(padded)); err != nil {
+			return
+		}
+		read += padded
+	}
+	return
+}
+
+// parseBitstreamHeader read size and alpha hint from the head of a VP8 or VP8L chunk payload
+func parseBitstreamHeader(fourcc FourCC, b []byte) (w, h int, alpha, ok bool) {
+	if fourcc == vp8lFourCC {
+		// signature(8) width-1(14) height-1(14) alpha(1) version(3)
+		if len(b) < 5 || b[0] != 0x2f {
+			return
+		}
+		bits := binary.LittleEndian.Uint32(b[1:])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, bits>>28&1 == 1, bits>>29 == 0
+	}
+	// frame tag(3) start code(3) width(14+2) height(14+2)
+	if len(b) < 10 || b[3] != 0x9d || b[4] != 0x01 || b[5] != 0x2a {
+		return
+	}
+	return int(binary.LittleEndian.Uint16(b[6:]) & 0x3fff), int(binary.LittleEndian.Uint16(b[8:]) & 0x3fff), false, true
+}
+
+func readChunkHeader(r io.Reader) (fourcc FourCC, size uint32, err error) {
+	var b [8]byte
+	if _, err = io.ReadFull(r, b[:]); err != nil {
+		return
+	}
+	copy(fourcc[:], b[:4])
+	size = binary.LittleEndian.Uint32(b[4:])
+	return
+}
+
+// readPayload read the head of a chunk payload into buf, never more than size bytes
+func readPayload(r io.Reader, buf []byte, size uint32) (int, error) {
+	if int(size) < len(buf) {
+		buf = buf[:size]
+	}
+	return io.ReadFull(r, buf)
+}
+
+func skip(r io.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		//a pipe or stdin implement io.Seeker but fail to seek, it is read through like a plain reader
+		if cur, err := s.Seek(0, io.SeekCurrent); err == nil {
+			//seeking past the end is not an error, check it so a truncated file fail like with a plain reader
+			end, err := s.Seek(0, io.SeekEnd)
+			if err != nil {
+				return err
+			}
+			if cur+n > end {
+				return io.ErrUnexpectedEOF
+			}
+			_, err = s.Seek(cur+n, io.SeekStart)
+			return err
+		}
+	}
+	_, err := io.CopyN(ioutil.Discard, r, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func fourCCAt(b []byte, off int, fourcc FourCC) bool {
+	return b[off] == fourcc[0] && b[off+1] == fourcc[1] && b[off+2] == fourcc[2] && b[off+3] == fourcc[3]
+}
+
+func getLE24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
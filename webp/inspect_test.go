@@ -0,0 +1,166 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func testChunk(fourcc string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(data)+1)
+	copy(b, fourcc)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	b = append(b, data...)
+	if len(data)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func testRIFF(chunks ...[]byte) []byte {
+	data := bytes.Join(chunks, nil)
+	b := make([]byte, 12, 12+len(data))
+	copy(b, "RIFF")
+	binary.LittleEndian.PutUint32(b[4:], uint32(4+len(data)))
+	copy(b[8:], "WEBP")
+	return append(b, data...)
+}
+
+func testVP8(w, h int) []byte {
+	b := []byte{0x50, 0x01, 0x00, 0x9d, 0x01, 0x2a, 0, 0, 0, 0, 0xaa, 0xbb, 0xcc}
+	binary.LittleEndian.PutUint16(b[6:], uint16(w))
+	binary.LittleEndian.PutUint16(b[8:], uint16(h))
+	return testChunk("VP8 ", b)
+}
+
+func testVP8L(w, h int, alpha bool) []byte {
+	bits := uint32(w-1) | uint32(h-1)<<14
+	if alpha {
+		bits |= 1 << 28
+	}
+	b := []byte{0x2f, 0, 0, 0, 0, 0xaa, 0xbb}
+	binary.LittleEndian.PutUint32(b[1:], bits)
+	return testChunk("VP8L", b)
+}
+
+func testVP8X(flags FeatureFlags, w, h int) []byte {
+	b := make([]byte, 10)
+	b[0] = byte(flags)
+	b[4], b[5], b[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+	b[7], b[8], b[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+	return testChunk("VP8X", b)
+}
+
+func testANMF(frame []byte) []byte {
+	return testChunk("ANMF", make([]byte, 16), frame)
+}
+
+// plainReader hide the io.Seeker of the underlying reader
+type plainReader struct {
+	io.Reader
+}
+
+// pipeReader implement io.Seeker but fail to seek, like *os.File on a pipe
+type pipeReader struct {
+	io.Reader
+}
+
+func (pipeReader) Seek(int64, int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func TestInspect(t *testing.T) {
+	lossy := testRIFF(testVP8(300, 200))
+	animated := testRIFF(
+		testVP8X(AnimationFlag|AlphaFlag, 64, 48),
+		testChunk("ANIM", []byte{0, 0, 0, 0, 3, 0}),
+		testANMF(testVP8(64, 48)),
+		testANMF(testVP8L(64, 48, true)),
+		testChunk("EXIF", make([]byte, 7)),
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+		want *ContainerFeatures
+		err  error
+	}{
+		{
+			name: "simple lossy",
+			data: lossy,
+			want: &ContainerFeatures{Width: 300, Height: 200, Format: FormatLossy, FrameCount: 1, Metadata: map[FourCC]int{}},
+		},
+		{
+			name: "simple lossless",
+			data: testRIFF(testVP8L(17, 9, true)),
+			want: &ContainerFeatures{Width: 17, Height: 9, Format: FormatLossless, HasAlpha: true, FrameCount: 1, Metadata: map[FourCC]int{}},
+		},
+		{
+			name: "extended with ICCP before image",
+			data: testRIFF(
+				testVP8X(ICCPFlag|AlphaFlag, 640, 480),
+				testChunk("ICCP", make([]byte, 123)),
+				testChunk("ALPH", make([]byte, 5)),
+				testVP8(640, 480),
+			),
+			want: &ContainerFeatures{
+				Width: 640, Height: 480, Extended: true, Flags: ICCPFlag | AlphaFlag, Format: FormatLossy,
+				HasAlpha: true, FrameCount: 1, Metadata: map[FourCC]int{ICCP: 123},
+			},
+		},
+		{
+			name: "animated",
+			data: animated,
+			want: &ContainerFeatures{
+				Width: 64, Height: 48, Extended: true, Flags: AnimationFlag | AlphaFlag, Format: FormatMixed,
+				HasAlpha: true, HasAnimation: true, FrameCount: 2, LoopCount: 3, Metadata: map[FourCC]int{EXIF: 7},
+			},
+		},
+		{
+			name: "truncated in chunk payload",
+			data: lossy[:len(lossy)-2],
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated in chunk header",
+			data: animated[:12+18+4],
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated in animation frame",
+			data: animated[:len(animated)-len(testChunk("EXIF", make([]byte, 7)))-3],
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "short animation frame",
+			data: testRIFF(
+				testVP8X(AnimationFlag, 64, 48),
+				testChunk("ANMF", make([]byte, 10)),
+				testVP8(64, 48),
+			),
+			err: VP8StatusBitstreamError.error("Inspect: invalid ANMF chunk, "),
+		},
+	}
+
+	for _, tt := range tests {
+		readers := map[string]io.Reader{
+			"seeker": bytes.NewReader(tt.data),
+			"plain":  plainReader{bytes.NewReader(tt.data)},
+			"pipe":   pipeReader{bytes.NewReader(tt.data)},
+		}
+		for kind, r := range readers {
+			got, err := Inspect(r)
+			if err != tt.err {
+				t.Errorf("%s/%s: err = %v, want %v", tt.name, kind, err, tt.err)
+				continue
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s/%s: got %+v, want %+v", tt.name, kind, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package webp
+
+import (
+	"image"
+	"image/color"
+)
+
+// ExtraInfoType select the per macroblock info filled into WebPPicture.extra_info
+type ExtraInfoType int
+
+const (
+	ExtraInfoNone        ExtraInfoType = iota
+	ExtraInfoIntraType                 // intra type, 0: intra4, 1: intra16
+	ExtraInfoSegment                   // segment id
+	ExtraInfoQuant                     // quantizer
+	ExtraInfoIntra16Mode               // intra-16 prediction mode, 0xff for intra4 macroblocks
+	ExtraInfoChromaMode                // chroma prediction mode
+	ExtraInfoBitCost                   // bit cost
+	ExtraInfoDistortion                // distortion
+)
+
+// MacroblockInfo is a grid of one value per 16x16 macroblock in row major order
+type MacroblockInfo struct {
+	Type          ExtraInfoType
+	Width, Height int // in macroblocks
+	Values        []uint8
+}
+
+func (m *MacroblockInfo) At(x, y int) uint8 {
+	return m.Values[y*m.Width+x]
+}
+
+// Heatmap render every macroblock as a 16x16 block, colored from blue (lowest
+// value of the grid) to red (highest value).
+func (m *MacroblockInfo) Heatmap() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, m.Width*16, m.Height*16))
+	lo, hi := uint8(0xff), uint8(0)
+	for _, v := range m.Values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+
+	for my := 0; my < m.Height; my++ {
+		for mx := 0; mx < m.Width; mx++ {
+			var t float32
+			if hi > lo {
+				t = float32(m.At(mx, my)-lo) / float32(hi-lo)
+			}
+			c := heatColor(t)
+			for y := my * 16; y < my*16+16; y++ {
+				for x := mx * 16; x < mx*16+16; x++ {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// heatColor map t in [0, 1] through blue, cyan, green, yellow and red
+func heatColor(t float32) color.RGBA {
+	stops := [...][3]float32{{0, 0, 255}, {0, 255, 255}, {0, 255, 0}, {255, 255, 0}, {255, 0, 0}}
+	p := t * float32(len(stops)-1)
+	i := int(p)
+	if i >= len(stops)-1 {
+		i = len(stops) - 2
+	}
+	f := p - float32(i)
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{
+		R: uint8(a[0] + (b[0]-a[0])*f + 0.5),
+		G: uint8(a[1] + (b[1]-a[1])*f + 0.5),
+		B: uint8(a[2] + (b[2]-a[2])*f + 0.5),
+		A: 0xff,
+	}
+}
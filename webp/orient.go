@@ -0,0 +1,224 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// EXIF orientation, the value tell how to transform the stored image to display it upright
+type Orientation int
+
+const (
+	OrientUndefined  Orientation = iota
+	OrientNormal                 // 0th row at top, 0th column at left
+	OrientFlipH                  // flip horizontal
+	OrientRotate180              // rotate 180
+	OrientFlipV                  // flip vertical
+	OrientTranspose              // flip along the top-left to bottom-right diagonal
+	OrientRotate90               // rotate 90 clockwise
+	OrientTransverse             // flip along the top-right to bottom-left diagonal
+	OrientRotate270              // rotate 270 clockwise
+)
+
+const exifOrientationTag = 0x0112
+
+// GetOrientation read the orientation tag from the EXIF chunk of webp data,
+// return OrientNormal if there is no EXIF chunk or orientation tag.
+func GetOrientation(img []byte) (Orientation, error) {
+	exif, err := GetMetadata(img, EXIF)
+	if err == MuxNotFound {
+		return OrientNormal, nil
+	} else if err != nil {
+		return OrientUndefined, err
+	}
+	return parseExifOrientation(exif), nil
+}
+
+// parseExifOrientation walk IFD0 of a TIFF structured EXIF payload
+func parseExifOrientation(exif []byte) Orientation {
+	exif = bytes.TrimPrefix(exif, []byte("Exif\x00\x00"))
+	if len(exif) < 8 {
+		return OrientNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return OrientNormal
+	}
+
+	off := int(order.Uint32(exif[4:]))
+	if off < 8 || off+2 > len(exif) {
+		return OrientNormal
+	}
+	n := int(order.Uint16(exif[off:]))
+	for i, p := 0, off+2; i < n && p+12 <= len(exif); i, p = i+1, p+12 {
+		if order.Uint16(exif[p:]) != exifOrientationTag {
+			continue
+		}
+		// SHORT value is stored left justified in the 4 bytes value field
+		if o := Orientation(order.Uint16(exif[p+8:])); o >= OrientNormal && o <= OrientRotate270 {
+			return o
+		}
+		break
+	}
+	return OrientNormal
+}
+
+// swapSize report whether the orientation swap width and height
+func (o Orientation) swapSize() bool {
+	return o >= OrientTranspose && o <= OrientRotate270
+}
+
+// srcPoint map point (x, y) of the upright image to the stored w x h image
+func (o Orientation) srcPoint(x, y, w, h int) (int, int) {
+	switch o {
+	case OrientFlipH:
+		return w - 1 - x, y
+	case OrientRotate180:
+		return w - 1 - x, h - 1 - y
+	case OrientFlipV:
+		return x, h - 1 - y
+	case OrientTranspose:
+		return y, x
+	case OrientRotate90:
+		return y, h - 1 - x
+	case OrientTransverse:
+		return w - 1 - y, h - 1 - x
+	case OrientRotate270:
+		return w - 1 - y, x
+	}
+	return x, y
+}
+
+// applyOrientation transform a decoded image to be displayed upright,
+// the returned image keep the type of img.
+func applyOrientation(img image.Image, o Orientation) image.Image {
+	if o <= OrientNormal || o > OrientRotate270 {
+		return img
+	}
+
+	switch m := img.(type) {
+	case *RGBImg:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 3, o)
+		return &RGBImg{Pix: pix, Stride: stride, Rect: r}
+	case *image.RGBA:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 4, o)
+		return &image.RGBA{Pix: pix, Stride: stride, Rect: r}
+	case *image.NRGBA:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 4, o)
+		return &image.NRGBA{Pix: pix, Stride: stride, Rect: r}
+	case *image.RGBA64:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 8, o)
+		return &image.RGBA64{Pix: pix, Stride: stride, Rect: r}
+	case *image.NRGBA64:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 8, o)
+		return &image.NRGBA64{Pix: pix, Stride: stride, Rect: r}
+	case *image.Gray:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 1, o)
+		return &image.Gray{Pix: pix, Stride: stride, Rect: r}
+	case *image.Alpha:
+		r := orientRect(m.Rect, o)
+		pix, stride := orientPix(m.Pix, m.Stride, m.Rect, 1, o)
+		return &image.Alpha{Pix: pix, Stride: stride, Rect: r}
+	case *YCbCr:
+		if y := orientYCbCr(&m.YCbCr, o); y != nil {
+			return &YCbCr{YCbCr: *y}
+		}
+	case *NYCbCrA:
+		if y := orientYCbCr(&m.YCbCr, o); y != nil {
+			a, _ := orientPix(m.A, m.AStride, m.Rect, 1, o)
+			return &NYCbCrA{NYCbCrA: image.NYCbCrA{YCbCr: *y, A: a, AStride: y.YStride}}
+		}
+	case *image.YCbCr:
+		if y := orientYCbCr(m, o); y != nil {
+			return y
+		}
+	case *image.NYCbCrA:
+		if y := orientYCbCr(&m.YCbCr, o); y != nil {
+			a, _ := orientPix(m.A, m.AStride, m.Rect, 1, o)
+			return &image.NYCbCrA{YCbCr: *y, A: a, AStride: y.YStride}
+		}
+	}
+
+	//fallback for other image types, slow path
+	rect := img.Bounds()
+	dst := image.NewNRGBA64(orientRect(rect, o))
+	w, h := rect.Dx(), rect.Dy()
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			sx, sy := o.srcPoint(x, y, w, h)
+			dst.Set(x, y, color.NRGBA64Model.Convert(img.At(rect.Min.X+sx, rect.Min.Y+sy)))
+		}
+	}
+	return dst
+}
+
+func orientRect(r image.Rectangle, o Orientation) image.Rectangle {
+	if o.swapSize() {
+		return image.Rect(0, 0, r.Dy(), r.Dx())
+	}
+	return image.Rect(0, 0, r.Dx(), r.Dy())
+}
+
+// orientPix transform interleaved pixels with bpp bytes per pixel
+func orientPix(pix []uint8, stride int, rect image.Rectangle, bpp int, o Orientation) ([]uint8, int) {
+	w, h := rect.Dx(), rect.Dy()
+	dw, dh := w, h
+	if o.swapSize() {
+		dw, dh = h, w
+	}
+	dstStride := dw * bpp
+	dst := make([]uint8, dstStride*dh)
+	for y := 0; y < dh; y++ {
+		row := dst[y*dstStride : (y+1)*dstStride]
+		for x := 0; x < dw; x++ {
+			sx, sy := o.srcPoint(x, y, w, h)
+			off := sy*stride + sx*bpp
+			copy(row[x*bpp:(x+1)*bpp], pix[off:off+bpp])
+		}
+	}
+	return dst, dstStride
+}
+
+// orientYCbCr transform planar image, return nil if the subsample ratio can not be kept
+func orientYCbCr(m *image.YCbCr, o Orientation) *image.YCbCr {
+	ratio := m.SubsampleRatio
+	if o.swapSize() {
+		switch ratio {
+		case image.YCbCrSubsampleRatio422:
+			ratio = image.YCbCrSubsampleRatio440
+		case image.YCbCrSubsampleRatio440:
+			ratio = image.YCbCrSubsampleRatio422
+		case image.YCbCrSubsampleRatio411, image.YCbCrSubsampleRatio410:
+			return nil
+		}
+	}
+
+	rect := m.Rect
+	w, h := rect.Dx(), rect.Dy()
+	dst := image.NewYCbCr(orientRect(rect, o), ratio)
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			sx, sy := o.srcPoint(x, y, w, h)
+			sx, sy = sx+rect.Min.X, sy+rect.Min.Y
+			dst.Y[dst.YOffset(x, y)] = m.Y[m.YOffset(sx, sy)]
+			ci, sci := dst.COffset(x, y), m.COffset(sx, sy)
+			dst.Cb[ci] = m.Cb[sci]
+			dst.Cr[ci] = m.Cr[sci]
+		}
+	}
+	return dst
+}
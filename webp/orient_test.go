@@ -0,0 +1,35 @@
+package webp
+
+import (
+	"image"
+	"testing"
+)
+
+func TestApplyOrientation(t *testing.T) {
+	const w, h = 6, 4
+	src := image.NewNYCbCrA(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio444)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.Y[src.YOffset(x, y)] = uint8(x*16 + y)
+			src.Cb[src.COffset(x, y)] = uint8(x + 100)
+			src.Cr[src.COffset(x, y)] = uint8(y + 100)
+			src.A[src.AOffset(x, y)] = uint8(x*40 + y)
+		}
+	}
+
+	for o := OrientFlipH; o <= OrientRotate270; o++ {
+		dst, ok := applyOrientation(src, o).(*image.NYCbCrA)
+		if !ok {
+			t.Fatalf("orientation %d: got %T, want *image.NYCbCrA", o, applyOrientation(src, o))
+		}
+		r := dst.Bounds()
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				sx, sy := o.srcPoint(x, y, w, h)
+				if got, want := dst.At(x, y), src.At(sx, sy); got != want {
+					t.Fatalf("orientation %d: pixel (%d, %d) is %v, want %v", o, x, y, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include "webp.h"
+*/
+import "C"
+import (
+	"image"
+	"io"
+)
+
+// Picture is an image imported once into libwebp and encoded any number of times.
+// The pixels are copied to C memory, so img may be modified or released after NewPicture.
+// Every encode work on a copy of the picture, libwebp modify the pixels in place when
+// converting to YUV or cleaning the transparent area, so encodes don't affect each other.
+// A Picture must be released by Close and must not be used concurrently.
+type Picture struct {
+	pic *C.WebPPicture
+}
+
+// NewPicture import img, 4:2:0 *YCbCr and *NYCbCrA are kept as YUV, other images as ARGB
+func NewPicture(img image.Image) (*Picture, error) {
+	var src C.WebPPicture
+	defer C.WebPPictureFree(&src)
+
+	holder, err := webpPictureImport(&src, img, &EncodeOptions{Lossless: true})
+	if err != nil {
+		return nil, err
+	}
+
+	pic := C.GoAllocWebPPicture()
+	if pic == nil {
+		return nil, VP8EncErrorOutOfMemory
+	}
+	var ok C.int
+	if holder == nil {
+		ok = C.WebPPictureCopy(&src, pic)
+	} else {
+		ok = C.GoWebPPictureCopyUseGoMem(&src, *holder, pic)
+	}
+	if int(ok) == 0 {
+		freePicture(pic)
+		return nil, VP8EncErrorOutOfMemory
+	}
+	return &Picture{pic: pic}, nil
+}
+
+// Bounds return an empty rectangle after Close
+func (p *Picture) Bounds() image.Rectangle {
+	if p.pic == nil {
+		return image.Rectangle{}
+	}
+	return image.Rect(0, 0, int(p.pic.width), int(p.pic.height))
+}
+
+// Close release the C memory of the picture, it is safe to call Close more than once
+func (p *Picture) Close() {
+	if p.pic != nil {
+		freePicture(p.pic)
+		p.pic = nil
+	}
+}
+
+// Encode is like the package level Encode, without importing the image again
+func (p *Picture) Encode(w io.Writer, opts *EncodeOptions) error {
+	_, err := p.encodeTo(w, opts, nil)
+	return err
+}
+
+// EncodeWithStats is like the package level EncodeWithStats, without importing the image again
+func (p *Picture) EncodeWithStats(w io.Writer, opts *EncodeOptions) (*EncodeStats, error) {
+	return collectStats(func(stats *C.WebPAuxStats) (*MacroblockInfo, error) {
+		return p.encodeTo(w, opts, stats)
+	})
+}
+
+// EncodeSlice is like the package level EncodeSlice, without importing the image again
+func (p *Picture) EncodeSlice(opts *EncodeOptions) ([]byte, error) {
+	var config C.WebPConfig
+	var work C.WebPPicture
+	defer C.WebPPictureFree(&work)
+	if err := p.setupEncode(&work, &config, opts); err != nil {
+		return nil, err
+	}
+
+	var out *C.uint8_t
+	outSize := C.GoWebPEncode(&work, &config, &out)
+	if int(outSize) == 0 {
+		return nil, VP8EncodeError(work.error_code)
+	}
+	return wrapUnsafeBytes(out, outSize).asSafe(), nil
+}
+
+func (p *Picture) encodeTo(w io.Writer, opts *EncodeOptions, stats *C.WebPAuxStats) (*MacroblockInfo, error) {
+	var config C.WebPConfig
+	var work C.WebPPicture
+	defer C.WebPPictureFree(&work)
+	if err := p.setupEncode(&work, &config, opts); err != nil {
+		return nil, err
+	}
+	return encodePicTo(w, &work, &config, nil, opts, stats)
+}
+
+// setupEncode validate opts into config and copy the picture into work
+func (p *Picture) setupEncode(work *C.WebPPicture, config *C.WebPConfig, opts *EncodeOptions) error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	opts.assign(config)
+	if !validateEncodeConfig(config) {
+		return VP8EncErrorInvalidConfiguration
+	}
+	if int(C.WebPPictureCopy(p.pic, work)) == 0 {
+		return VP8EncErrorOutOfMemory
+	}
+	return nil
+}
+
+// Clone return a deep copy of the picture, e.g. to produce several crops or sizes from one import
+func (p *Picture) Clone() (*Picture, error) {
+	if p.pic == nil {
+		return nil, VP8EncErrorNullParameter
+	}
+	pic := C.GoAllocWebPPicture()
+	if pic == nil {
+		return nil, VP8EncErrorOutOfMemory
+	}
+	if int(C.WebPPictureCopy(p.pic, pic)) == 0 {
+		freePicture(pic)
+		return nil, VP8EncErrorOutOfMemory
+	}
+	return &Picture{pic: pic}, nil
+}
+
+// Crop crop the picture to r in place, r must be inside Bounds.
+// For YUV pictures the top left corner of r is snapped to even coordinates.
+func (p *Picture) Crop(r image.Rectangle) error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	if r.Empty() || !r.In(p.Bounds()) {
+		return VP8EncErrorBadDimension
+	}
+	if int(C.WebPPictureCrop(p.pic, C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()))) == 0 {
+		return VP8EncErrorOutOfMemory
+	}
+	return nil
+}
+
+// Rescale rescale the picture in place, if either width or height is 0
+// it is computed to keep the aspect ratio.
+func (p *Picture) Rescale(width, height int) error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	if width < 0 || height < 0 || width == 0 && height == 0 {
+		return VP8EncErrorBadDimension
+	}
+	if int(C.WebPPictureRescale(p.pic, C.int(width), C.int(height))) == 0 {
+		return VP8EncErrorOutOfMemory
+	}
+	return nil
+}
+
+// View return a picture sharing the pixels of r with p, nothing is copied.
+// The view is valid until p is closed, cropped or rescaled, Crop and Rescale
+// on the view allocate its own memory and leave p untouched.
+// For YUV pictures the top left corner of r is snapped to even coordinates.
+func (p *Picture) View(r image.Rectangle) (*Picture, error) {
+	if p.pic == nil {
+		return nil, VP8EncErrorNullParameter
+	}
+	if r.Empty() || !r.In(p.Bounds()) {
+		return nil, VP8EncErrorBadDimension
+	}
+	pic := C.GoAllocWebPPicture()
+	if pic == nil {
+		return nil, VP8EncErrorOutOfMemory
+	}
+	if int(C.WebPPictureView(p.pic, C.int(r.Min.X), C.int(r.Min.Y), C.int(r.Dx()), C.int(r.Dy()), pic)) == 0 {
+		freePicture(pic)
+		return nil, VP8EncErrorBadDimension
+	}
+	return &Picture{pic: pic}, nil
+}
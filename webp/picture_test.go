@@ -0,0 +1,41 @@
+package webp
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPictureClosed(t *testing.T) {
+	p, err := NewPicture(testARGB(16, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Bounds(); got != image.Rect(0, 0, 16, 16) {
+		t.Fatalf("Bounds %v, want 16x16", got)
+	}
+	p.Close()
+	p.Close()
+
+	if got := p.Bounds(); !got.Empty() {
+		t.Errorf("Bounds after Close %v, want empty", got)
+	}
+	opts, err := NewEncOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.EncodeSlice(opts); err != VP8EncErrorNullParameter {
+		t.Errorf("EncodeSlice: err = %v, want %v", err, VP8EncErrorNullParameter)
+	}
+	if _, err = p.Clone(); err != VP8EncErrorNullParameter {
+		t.Errorf("Clone: err = %v, want %v", err, VP8EncErrorNullParameter)
+	}
+	if err = p.Crop(image.Rect(0, 0, 8, 8)); err != VP8EncErrorNullParameter {
+		t.Errorf("Crop: err = %v, want %v", err, VP8EncErrorNullParameter)
+	}
+	if err = p.Rescale(8, 8); err != VP8EncErrorNullParameter {
+		t.Errorf("Rescale: err = %v, want %v", err, VP8EncErrorNullParameter)
+	}
+	if _, err = p.View(image.Rect(0, 0, 8, 8)); err != VP8EncErrorNullParameter {
+		t.Errorf("View: err = %v, want %v", err, VP8EncErrorNullParameter)
+	}
+}
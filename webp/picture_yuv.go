@@ -0,0 +1,105 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include "webp.h"
+*/
+import "C"
+import (
+	"image"
+	"unsafe"
+)
+
+// UseARGB report whether the picture hold ARGB pixels rather than YUV planes
+func (p *Picture) UseARGB() bool {
+	return p.pic != nil && p.pic.use_argb != 0
+}
+
+// ARGBToYUVA convert the ARGB pixels to YUV420 in place, an alpha plane is kept if the picture has transparency
+func (p *Picture) ARGBToYUVA() error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	return p.checkConvert(C.WebPPictureARGBToYUVA(p.pic, C.WEBP_YUV420))
+}
+
+// ARGBToYUVADithered is like ARGBToYUVA with dithering strength in [0, 1]
+func (p *Picture) ARGBToYUVADithered(dithering float32) error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	return p.checkConvert(C.WebPPictureARGBToYUVADithered(p.pic, C.WEBP_YUV420, C.float(dithering)))
+}
+
+// SharpARGBToYUVA is like ARGBToYUVA with the slower and sharper conversion used by UseSharpYUV
+func (p *Picture) SharpARGBToYUVA() error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	return p.checkConvert(C.WebPPictureSharpARGBToYUVA(p.pic))
+}
+
+// YUVAToARGB convert the YUV planes to ARGB in place
+func (p *Picture) YUVAToARGB() error {
+	if p.pic == nil {
+		return VP8EncErrorNullParameter
+	}
+	return p.checkConvert(C.WebPPictureYUVAToARGB(p.pic))
+}
+
+func (p *Picture) checkConvert(ok C.int) error {
+	if int(ok) == 0 {
+		return VP8EncodeError(p.pic.error_code)
+	}
+	return nil
+}
+
+// YUVA copy the YUV planes into a *YCbCr, or a *NYCbCrA if the picture has an
+// alpha plane, return nil if the picture hold ARGB pixels.
+// The result can be passed to NewPicture or Encode without conversion.
+func (p *Picture) YUVA() image.Image {
+	if p.pic == nil || p.pic.use_argb != 0 || p.pic.y == nil {
+		return nil
+	}
+	r := p.Bounds()
+	w, h := r.Dx(), r.Dy()
+	cw, ch := (w+1)/2, (h+1)/2
+
+	var m *image.YCbCr
+	var ret image.Image
+	if p.pic.colorspace&C.WEBP_CSP_ALPHA_BIT != 0 && p.pic.a != nil {
+		img := NewNYCbCrA(r)
+		copyPlane(img.A, img.AStride, p.pic.a, int(p.pic.a_stride), w, h)
+		m, ret = &img.YCbCr, img
+	} else {
+		img := NewYCbCr(r)
+		m, ret = &img.YCbCr, img
+	}
+	copyPlane(m.Y, m.YStride, p.pic.y, int(p.pic.y_stride), w, h)
+	copyPlane(m.Cb, m.CStride, p.pic.u, int(p.pic.uv_stride), cw, ch)
+	copyPlane(m.Cr, m.CStride, p.pic.v, int(p.pic.uv_stride), cw, ch)
+	return ret
+}
+
+// ARGB copy the ARGB pixels, return nil if the picture hold YUV planes
+func (p *Picture) ARGB() *ARGBImg {
+	if p.pic == nil || p.pic.use_argb == 0 || p.pic.argb == nil {
+		return nil
+	}
+	r := p.Bounds()
+	w, h, stride := r.Dx(), r.Dy(), int(p.pic.argb_stride)
+	n := (h-1)*stride + w
+	src := (*(*[1 << 28]uint32)(unsafe.Pointer(p.pic.argb)))[:n:n]
+	img := &ARGBImg{Pix: make([]uint32, w*h), Stride: w, Rect: r}
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*w:(y+1)*w], src[y*stride:])
+	}
+	return img
+}
+
+func copyPlane(dst []uint8, dstStride int, src *C.uint8_t, srcStride, w, h int) {
+	pix := wrapUnsafeBytes(src, C.size_t((h-1)*srcStride+w))
+	for y := 0; y < h; y++ {
+		copy(dst[y*dstStride:y*dstStride+w], pix[y*srcStride:])
+	}
+}
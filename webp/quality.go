@@ -0,0 +1,97 @@
+package webp
+
+/*
+#include "webp.h"
+*/
+import "C"
+import "image"
+
+type QualitySearchResult struct {
+	Quality float32
+	Method  int
+	// score of the chosen output, in the unit of the target metric
+	Score float32
+	Size  int
+}
+
+// EncodeToQuality binary search the lowest lossy Quality whose output reach
+// target measured by metric against img, SSIM target is in [0, 1], PSNR and
+// LSIM targets are in dB. Every method of methods is searched and the smallest
+// output wins, opts.Method is used if methods is empty. If even Quality 100
+// can't reach the target, the Quality 100 output is returned and Score tell
+// how far it is.
+func EncodeToQuality(img image.Image, metric DistortionMetric, target float32, opts *EncodeOptions, methods ...int) ([]byte, *QualitySearchResult, error) {
+	//imported like Distortion does, so the scores agree with it
+	ref, err := NewPicture(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer ref.Close()
+
+	if len(methods) == 0 {
+		methods = []int{opts.Method}
+	}
+
+	var best []byte
+	var bestResult *QualitySearchResult
+	for _, method := range methods {
+		o := *opts
+		o.Lossless = false
+		o.Method = method
+		data, result, err := searchQuality(ref.pic, img, metric, metric.toDB(target), &o)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bestResult == nil || result.Size < bestResult.Size {
+			best, bestResult = data, result
+		}
+	}
+	return best, bestResult, nil
+}
+
+func searchQuality(ref *C.WebPPicture, img image.Image, metric DistortionMetric, targetDB float32, opts *EncodeOptions) ([]byte, *QualitySearchResult, error) {
+	trial := func(q int) ([]byte, float32, error) {
+		opts.Quality = float32(q)
+		data, err := EncodeSlice(img, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		pic, err := newPictureFromWebP(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer freePicture(pic)
+		d, err := pictureDistortion(ref, pic, metric)
+		return data, d[4], err
+	}
+
+	//the highest quality is the fallback if the target can't be reached
+	data, score, err := trial(100)
+	if err != nil {
+		return nil, nil, err
+	}
+	quality := 100
+
+	lo, hi := -1, 100 //score(lo) < target <= score(hi)
+	if score >= targetDB {
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			d, s, err := trial(mid)
+			if err != nil {
+				return nil, nil, err
+			}
+			if s >= targetDB {
+				hi, data, score, quality = mid, d, s, mid
+			} else {
+				lo = mid
+			}
+		}
+	}
+
+	return data, &QualitySearchResult{
+		Quality: float32(quality),
+		Method:  opts.Method,
+		Score:   metric.fromDB(score),
+		Size:    len(data),
+	}, nil
+}
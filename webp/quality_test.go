@@ -0,0 +1,34 @@
+package webp
+
+import "testing"
+
+func TestEncodeToQuality(t *testing.T) {
+	img := testNYCbCrA(64, 64)
+	for i := range img.A {
+		img.A[i] = 0xff
+	}
+	opts, err := NewEncOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, result, err := EncodeToQuality(img, MetricSSIM, 0.98, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score < 0.98 {
+		t.Errorf("Score %v is below the target", result.Score)
+	}
+
+	// the search must measure like Distortion does
+	decoded, err := DecodeSlice(data, NewDecOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Distortion(img, decoded, MetricSSIM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != d.All {
+		t.Errorf("Score %v, Distortion %v", result.Score, d.All)
+	}
+}
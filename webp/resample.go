@@ -0,0 +1,193 @@
+package webp
+
+/*
+#include <webp/decode.h>
+*/
+import "C"
+import (
+	"github.com/mocukie/webp-go/webp/colorx"
+	"image"
+	"math"
+)
+
+type ResampleFilter int
+
+const (
+	ResampleNone     ResampleFilter = iota // use the libwebp rescaler
+	ResampleBox                            // box filter, average of covered pixels
+	ResampleLanczos3                       // Lanczos windowed sinc, a = 3
+	ResampleMitchell                       // Mitchell-Netravali cubic, B = C = 1/3
+)
+
+func (f ResampleFilter) support() float64 {
+	switch f {
+	case ResampleLanczos3:
+		return 3
+	case ResampleMitchell:
+		return 2
+	}
+	return 0.5
+}
+
+func (f ResampleFilter) at(x float64) float64 {
+	x = math.Abs(x)
+	switch f {
+	case ResampleLanczos3:
+		if x == 0 {
+			return 1
+		} else if x >= 3 {
+			return 0
+		}
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	case ResampleMitchell:
+		const b, c = 1.0 / 3, 1.0 / 3
+		if x < 1 {
+			return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+		} else if x < 2 {
+			return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+		}
+		return 0
+	}
+	if x < 0.5 {
+		return 1
+	}
+	return 0
+}
+
+// contrib is the source span and normalized weights of one destination sample
+type contrib struct {
+	start  int
+	weight []float32
+}
+
+func (f ResampleFilter) contribs(srcSize, dstSize int) []contrib {
+	scale := float64(srcSize) / float64(dstSize)
+	fscale := math.Max(scale, 1) // widen the kernel when downscaling
+	support := f.support() * fscale
+	cs := make([]contrib, dstSize)
+	for i := range cs {
+		center := (float64(i) + 0.5) * scale
+		start := int(math.Floor(center - support))
+		end := int(math.Ceil(center + support))
+		if start < 0 {
+			start = 0
+		}
+		if end > srcSize {
+			end = srcSize
+		}
+		w := make([]float32, end-start)
+		var sum float64
+		for j := range w {
+			v := f.at((float64(start+j) + 0.5 - center) / fscale)
+			w[j] = float32(v)
+			sum += v
+		}
+		if sum != 0 {
+			for j := range w {
+				w[j] = float32(float64(w[j]) / sum)
+			}
+		}
+		cs[i] = contrib{start: start, weight: w}
+	}
+	return cs
+}
+
+// linearScaler decode to image.NRGBA at full size, then resample in linear light
+type linearScaler struct {
+	*image.NRGBA
+	width, height int
+	filter        ResampleFilter
+	hasAlpha      bool
+}
+
+func newLinearScaler(config *C.WebPDecoderConfig, srcW, srcH, dstW, dstH int, filter ResampleFilter) *linearScaler {
+	img := image.NewNRGBA(image.Rect(0, 0, srcW, srcH))
+	setupRGBBuf(config, img.Pix, img.Stride, ModeNRGBA)
+	return &linearScaler{
+		NRGBA:    img,
+		width:    dstW,
+		height:   dstH,
+		filter:   filter,
+		hasAlpha: int(config.input.has_alpha) == 1,
+	}
+}
+
+// convert return image.NRGBA if has alpha, otherwise image.RGBA, like TypeStd
+func (p *linearScaler) convert() image.Image {
+	src := p.NRGBA
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := p.width, p.height
+
+	//alpha premultiplied linear light
+	lin := make([]float32, sw*sh*4)
+	for y, i := 0, 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+sw*4]
+		for x := 0; x < len(row); x, i = x+4, i+4 {
+			a := float32(row[x+3]) / 0xff
+			lin[i] = colorx.SRGBToLinear(row[x]) * a
+			lin[i+1] = colorx.SRGBToLinear(row[x+1]) * a
+			lin[i+2] = colorx.SRGBToLinear(row[x+2]) * a
+			lin[i+3] = a
+		}
+	}
+
+	//horizontal pass, sw x sh -> dw x sh
+	tmp := make([]float32, dw*sh*4)
+	for x, c := range p.filter.contribs(sw, dw) {
+		for y := 0; y < sh; y++ {
+			var r, g, b, a float32
+			s := (y*sw + c.start) * 4
+			for _, w := range c.weight {
+				r += lin[s] * w
+				g += lin[s+1] * w
+				b += lin[s+2] * w
+				a += lin[s+3] * w
+				s += 4
+			}
+			d := (y*dw + x) * 4
+			tmp[d], tmp[d+1], tmp[d+2], tmp[d+3] = r, g, b, a
+		}
+	}
+
+	//vertical pass, dw x sh -> dw x dh, then back to sRGB
+	rect := image.Rect(0, 0, dw, dh)
+	var pix []uint8
+	var stride int
+	var out image.Image
+	if p.hasAlpha {
+		m := image.NewNRGBA(rect)
+		pix, stride, out = m.Pix, m.Stride, m
+	} else {
+		m := image.NewRGBA(rect)
+		pix, stride, out = m.Pix, m.Stride, m
+	}
+	for y, c := range p.filter.contribs(sh, dh) {
+		row := pix[y*stride : y*stride+dw*4]
+		for x := 0; x < dw; x++ {
+			var r, g, b, a float32
+			s := (c.start*dw + x) * 4
+			for _, w := range c.weight {
+				r += tmp[s] * w
+				g += tmp[s+1] * w
+				b += tmp[s+2] * w
+				a += tmp[s+3] * w
+				s += dw * 4
+			}
+			d := x * 4
+			if !p.hasAlpha {
+				a = 1
+			} else if a <= 0 {
+				row[d], row[d+1], row[d+2], row[d+3] = 0, 0, 0, 0
+				continue
+			} else if a > 1 {
+				a = 1
+			}
+			row[d] = colorx.LinearToSRGB(r / a)
+			row[d+1] = colorx.LinearToSRGB(g / a)
+			row[d+2] = colorx.LinearToSRGB(b / a)
+			row[d+3] = uint8(a*0xff + 0.5)
+		}
+	}
+	return out
+}
@@ -63,7 +63,7 @@ func (p *ARGBImg) At(x, y int) color.Color {
 	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
 		return color.NRGBA{}
 	}
-	argb := p.Pix[(y-p.Rect.Min.Y)*p.Stride+(x-p.Rect.Min.X)*4]
+	argb := p.Pix[(y-p.Rect.Min.Y)*p.Stride+(x-p.Rect.Min.X)]
 	return color.NRGBA{
 		R: uint8(argb >> 16 & 0xff),
 		G: uint8(argb >> 8 & 0xff),
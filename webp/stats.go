@@ -0,0 +1,99 @@
+package webp
+
+/*
+#include <webp/encode.h>
+*/
+import "C"
+import "time"
+
+// LosslessFeatures tell which transforms the lossless encoder used
+type LosslessFeatures uint32
+
+const (
+	LosslessPredictor LosslessFeatures = 1 << iota
+	LosslessCrossColor
+	LosslessSubtractGreen
+	LosslessColorIndexing
+)
+
+// EncodeStats mirror WebPAuxStats
+type EncodeStats struct {
+	// final size
+	CodedSize int
+	// peak-signal-to-noise ratio for Y/U/V/All/Alpha
+	PSNR [5]float32
+	// number of intra4/intra16/skipped macroblocks
+	BlockCount [3]int
+	// approximate number of bytes spent for header and mode-partition #0
+	HeaderBytes [2]int
+	// approximate number of bytes spent for DC/AC/uv coefficients for each (0..3) segments
+	ResidualBytes [3][4]int
+	// number of macroblocks in each segments
+	SegmentSize [4]int
+	// quantizer values for each segments
+	SegmentQuant [4]int
+	// filtering strength for each segments [0..63]
+	SegmentLevel [4]int
+	// size of the transparency data
+	AlphaDataSize int
+	// size of the enhancement layer data
+	LayerDataSize int
+
+	// lossless encoder statistics
+	LosslessFeatures LosslessFeatures
+	// number of precision bits of histogram
+	HistogramBits int
+	// precision bits for transform
+	TransformBits int
+	// number of bits for color cache lookup
+	CacheBits int
+	// number of color in palette, if used
+	PaletteSize int
+	// final lossless size
+	LosslessSize int
+	// lossless header (transform, huffman etc) size
+	LosslessHdrSize int
+	// lossless image data size
+	LosslessDataSize int
+
+	// wall time of the encode call, including writes to the io.Writer
+	Duration time.Duration
+	// per macroblock info selected by EncodeOptions.ExtraInfo, nil if not requested or lossless
+	MacroblockInfo *MacroblockInfo
+}
+
+func newEncodeStats(c *C.WebPAuxStats) *EncodeStats {
+	s := &EncodeStats{
+		CodedSize:        int(c.coded_size),
+		AlphaDataSize:    int(c.alpha_data_size),
+		LayerDataSize:    int(c.layer_data_size),
+		LosslessFeatures: LosslessFeatures(c.lossless_features),
+		HistogramBits:    int(c.histogram_bits),
+		TransformBits:    int(c.transform_bits),
+		CacheBits:        int(c.cache_bits),
+		PaletteSize:      int(c.palette_size),
+		LosslessSize:     int(c.lossless_size),
+		LosslessHdrSize:  int(c.lossless_hdr_size),
+		LosslessDataSize: int(c.lossless_data_size),
+	}
+	for i := range s.PSNR {
+		s.PSNR[i] = float32(c.PSNR[i])
+	}
+	for i := range s.BlockCount {
+		s.BlockCount[i] = int(c.block_count[i])
+	}
+	for i := range s.HeaderBytes {
+		s.HeaderBytes[i] = int(c.header_bytes[i])
+	}
+	for i := range s.ResidualBytes {
+		for j := range s.ResidualBytes[i] {
+			s.ResidualBytes[i][j] = int(c.residual_bytes[i][j])
+		}
+	}
+	for i := 0; i < 4; i++ {
+		s.SegmentSize[i] = int(c.segment_size[i])
+		s.SegmentQuant[i] = int(c.segment_quant[i])
+		s.SegmentLevel[i] = int(c.segment_level[i])
+	}
+	return s
+}
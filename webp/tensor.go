@@ -0,0 +1,114 @@
+package webp
+
+/*
+#cgo LDFLAGS: -lwebp
+#include <webp/decode.h>
+*/
+import "C"
+import (
+	"image"
+	"unsafe"
+)
+
+type TensorLayout int
+
+const (
+	LayoutCHW TensorLayout = iota // planar, one plane per channel
+	LayoutHWC                     // interleaved channels
+)
+
+type ChannelOrder int
+
+const (
+	ChannelRGB ChannelOrder = iota
+	ChannelBGR
+)
+
+type TensorOptions struct {
+	Layout TensorLayout
+	Order  ChannelOrder
+	// sample value v in [0, 255] is stored as (v/255 - Mean[c]) / Std[c], c in R, G, B order
+	Mean [3]float32
+	Std  [3]float32
+}
+
+func NewTensorOptions() *TensorOptions {
+	return &TensorOptions{Std: [3]float32{1, 1, 1}}
+}
+
+// DecodeTensor decode data into dst as a normalised float32 tensor of 3 channels,
+// the decoded RGB samples are converted straight from libwebp's output buffer
+// without allocating an image. dst is reallocated if it is too small, so a batch
+// tensor can be filled by passing sub slices of it.
+// Crop, Scale (with ScaleMode and ShrinkOnly) and Flip of opts are honored, ImageType,
+// AutoOrient, TargetProfile, LinearResample and Background are ignored.
+func DecodeTensor(data []byte, opts *DecodeOptions, t *TensorOptions, dst []float32) ([]float32, image.Point, error) {
+	cData, cSize := bytesGetCPtr(data)
+	config := &C.WebPDecoderConfig{}
+	if code := VP8StatusCode(C.WebPGetFeatures(cData, cSize, &config.input)); code != VP8StatusOk {
+		return nil, image.Point{}, code.error("could not get bits stream features, ")
+	}
+	opts.assign(&config.options, &config.input)
+	//libwebp flip its own buffer by a negative stride, fill the rows of dst in reverse instead
+	flip := int(config.options.flip) != 0
+	config.options.flip = 0
+	config.output.colorspace = C.WEBP_CSP_MODE(ModeRGB)
+	if code := VP8StatusCode(C.WebPDecode(cData, cSize, config)); code != VP8StatusOk {
+		return nil, image.Point{}, code.error("WebPDecode")
+	}
+	defer C.WebPFreeDecBuffer(&config.output)
+
+	width, height := int(config.output.width), int(config.output.height)
+	if n := width * height * 3; cap(dst) < n {
+		dst = make([]float32, n)
+	} else {
+		dst = dst[:n]
+	}
+
+	var lut [3][256]float32
+	for c := range lut {
+		std := t.Std[c]
+		if std == 0 {
+			std = 1
+		}
+		for v := range lut[c] {
+			lut[c][v] = (float32(v)/255 - t.Mean[c]) / std
+		}
+	}
+
+	// destination of R, G, B samples: index of first sample and step between pixels
+	var off [3]int
+	step := 1
+	for c := range off {
+		dc := c
+		if t.Order == ChannelBGR {
+			dc = 2 - c
+		}
+		if t.Layout == LayoutHWC {
+			off[c] = dc
+		} else {
+			off[c] = dc * width * height
+		}
+	}
+	if t.Layout == LayoutHWC {
+		step = 3
+	}
+
+	buf := (*C.WebPRGBABuffer)(unsafe.Pointer(&config.output.u[0]))
+	pix, stride := wrapUnsafeBytes(buf.rgba, buf.size), int(buf.stride)
+	r, g, b := dst[off[0]:], dst[off[1]:], dst[off[2]:]
+	for y := 0; y < height; y++ {
+		row := pix[y*stride : y*stride+width*3]
+		dy := y
+		if flip {
+			dy = height - 1 - y
+		}
+		i := dy * width * step
+		for x := 0; x < len(row); x, i = x+3, i+step {
+			r[i] = lut[0][row[x]]
+			g[i] = lut[1][row[x+1]]
+			b[i] = lut[2][row[x+2]]
+		}
+	}
+	return dst, image.Pt(width, height), nil
+}
@@ -1,4 +1,24 @@
 #include "webp.h"
+#include "_cgo_export.h"
+
+static int SetPixMem(WebPPicture* pic, PixMemHolder holder) {
+    if (holder.argb == NULL && holder.y == NULL)
+        return 0;
+    pic->argb = (uint32_t*)holder.argb;
+    pic->y = holder.y;
+    pic->u = holder.u;
+    pic->v = holder.v;
+    pic->a = holder.a;
+    return 1;
+}
+
+static void ResetPixMem(WebPPicture* pic) {
+    pic->argb = NULL;
+    pic->y = NULL;
+    pic->u = NULL;
+    pic->v = NULL;
+    pic->a = NULL;
+}
 
 size_t GoWebPEncode(WebPPicture* pic, const WebPConfig* config, uint8_t** output) {
     if (output == NULL)
@@ -35,6 +55,47 @@ size_t GoWebPEncodeUseGoMem(WebPPicture* pic, const WebPConfig* config, uint8_t*
     return r;
 }
 
+static int GoWebPWriterTrampoline(const uint8_t* data, size_t data_size, const WebPPicture* picture) {
+    return goWebPWrite((uint8_t*)data, data_size, (WebPPicture*)picture);
+}
+
+int GoWebPEncodeToWriter(WebPPicture* pic, const WebPConfig* config, uintptr_t writer) {
+    pic->writer = GoWebPWriterTrampoline;
+    pic->custom_ptr = (void*)writer;
+    int ok = WebPEncode(config, pic);
+    pic->writer = NULL;
+    pic->custom_ptr = NULL;
+    return ok;
+}
+
+int GoWebPEncodeToWriterUseGoMem(WebPPicture* pic, const WebPConfig* config, uintptr_t writer, PixMemHolder holder) {
+    SetPixMem(pic, holder);
+    int ok = GoWebPEncodeToWriter(pic, config, writer);
+    ResetPixMem(pic);
+    return ok;
+}
+
+int GoWebPPictureDistortionUseGoMem(WebPPicture* src, PixMemHolder src_holder,
+    WebPPicture* ref, PixMemHolder ref_holder, int type, float result[5]) {
+
+    int src_go_mem = SetPixMem(src, src_holder);
+    int ref_go_mem = SetPixMem(ref, ref_holder);
+    int ok = WebPPictureDistortion(src, ref, type, result);
+    if (src_go_mem)
+        ResetPixMem(src);
+    if (ref_go_mem)
+        ResetPixMem(ref);
+    return ok;
+}
+
+int GoWebPPictureCopyUseGoMem(WebPPicture* src, PixMemHolder holder, WebPPicture* dst) {
+    int go_mem = SetPixMem(src, holder);
+    int ok = WebPPictureCopy(src, dst);
+    if (go_mem)
+        ResetPixMem(src);
+    return ok;
+}
+
 WebPPicture* GoAllocWebPPicture() {
     WebPPicture* pic = malloc(sizeof(WebPPicture));
     if (!WebPPictureInit(pic)) {
@@ -75,13 +136,16 @@ WebPMuxError GoGetWebPChunk(uint8_t* img, size_t img_size, const char fourcc[4],
         return WEBP_MUX_INVALID_ARGUMENT;
     }
 
+    WebPMuxError ret = WEBP_MUX_NOT_FOUND;
     WebPChunkIterator it = {};
     if (WebPDemuxGetChunk(dmux, fourcc, 1, &it)) {
         *off = it.chunk.bytes - img;
         *size = it.chunk.size;
-        return WEBP_MUX_OK;
+        ret = WEBP_MUX_OK;
     }
-    return WEBP_MUX_NOT_FOUND;
+    WebPDemuxReleaseChunkIterator(&it);
+    WebPDemuxDelete(dmux);
+    return ret;
 }
 
 WebPMuxError GoDeleteWebPChunk(uint8_t* img, size_t img_size, const char fourcc[4]) {
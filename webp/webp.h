@@ -14,6 +14,29 @@ static int GoWebPDoImport(WebPPictureImporter import, WebPPicture* pic, const ui
     return import(pic, pix, stride);
 }
 
+// libwebp 1.2 replaced WebPConfig.pad with qmin and qmax and bumped
+// WEBP_ENCODER_ABI_VERSION to 0x020f
+#if WEBP_ENCODER_ABI_VERSION >= 0x020f
+#define GO_WEBP_HAS_QRANGE 1
+static void GoWebPConfigGetQRange(const WebPConfig* config, int* qmin, int* qmax) {
+    *qmin = config->qmin;
+    *qmax = config->qmax;
+}
+
+static void GoWebPConfigSetQRange(WebPConfig* config, int qmin, int qmax) {
+    config->qmin = qmin;
+    config->qmax = qmax;
+}
+#else
+#define GO_WEBP_HAS_QRANGE 0
+static void GoWebPConfigGetQRange(const WebPConfig* config, int* qmin, int* qmax) {
+    *qmin = 0;
+    *qmax = 100;
+}
+
+static void GoWebPConfigSetQRange(WebPConfig* config, int qmin, int qmax) {}
+#endif
+
 typedef struct PixMemHolder {
     uint8_t* y, *u, *v, *a;
     void* argb;
@@ -22,8 +45,16 @@ typedef struct PixMemHolder {
 size_t GoWebPEncode(WebPPicture* pic, const WebPConfig* config, uint8_t** output);
 size_t GoWebPEncodeUseGoMem(WebPPicture* pic, const WebPConfig* config, uint8_t** output, PixMemHolder holder);
 
+int GoWebPEncodeToWriter(WebPPicture* pic, const WebPConfig* config, uintptr_t writer);
+int GoWebPEncodeToWriterUseGoMem(WebPPicture* pic, const WebPConfig* config, uintptr_t writer, PixMemHolder holder);
+
+int GoWebPPictureCopyUseGoMem(WebPPicture* src, PixMemHolder holder, WebPPicture* dst);
+
 WebPPicture* GoAllocWebPPicture();
 
+int GoWebPPictureDistortionUseGoMem(WebPPicture* src, PixMemHolder src_holder,
+    WebPPicture* ref, PixMemHolder ref_holder, int type, float result[5]);
+
 static WebPData* GoAllocWebPData() {
     WebPData* data = malloc(sizeof(WebPData));
     return data;
@@ -0,0 +1,74 @@
+package webp
+
+/*
+#include <webp/encode.h>
+*/
+import "C"
+import (
+	"io"
+	"sync"
+)
+
+// EncodeWriteError is returned by Encode when the io.Writer failed while the
+// encoded data was streamed, errors.Is(err, VP8EncErrorBadWrite) report true.
+type EncodeWriteError struct {
+	Err error
+}
+
+func (e *EncodeWriteError) Error() string {
+	return VP8EncErrorBadWrite.Error() + ": " + e.Err.Error()
+}
+
+func (e *EncodeWriteError) Unwrap() error {
+	return e.Err
+}
+
+func (e *EncodeWriteError) Is(target error) bool {
+	return target == VP8EncErrorBadWrite
+}
+
+type picWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Go pointers can't be kept in WebPPicture.custom_ptr, so writers are
+// registered here and referenced by handle.
+var picWriters = struct {
+	sync.Mutex
+	m    map[uintptr]*picWriter
+	next uintptr
+}{m: make(map[uintptr]*picWriter)}
+
+func registerPicWriter(w io.Writer) (uintptr, *picWriter) {
+	pw := &picWriter{w: w}
+	picWriters.Lock()
+	defer picWriters.Unlock()
+	picWriters.next++
+	picWriters.m[picWriters.next] = pw
+	return picWriters.next, pw
+}
+
+func releasePicWriter(handle uintptr) {
+	picWriters.Lock()
+	delete(picWriters.m, handle)
+	picWriters.Unlock()
+}
+
+//export goWebPWrite
+func goWebPWrite(data *C.uint8_t, size C.size_t, pic *C.WebPPicture) C.int {
+	picWriters.Lock()
+	pw := picWriters.m[uintptr(pic.custom_ptr)]
+	picWriters.Unlock()
+	if pw == nil {
+		return 0
+	}
+	if size == 0 {
+		return 1
+	}
+	if _, err := pw.w.Write(wrapUnsafeBytes(data, size)); err != nil {
+		pw.err = err
+		return 0
+	}
+	return 1
+}
@@ -87,3 +87,55 @@ func NewNYCbCrA(r image.Rectangle) *NYCbCrA {
 		NYCbCrA: *image.NewNYCbCrA(r, image.YCbCrSubsampleRatio420),
 	}
 }
+
+// upsample420 return a 4:4:4 image from a 4:2:0 image at the origin, chroma is
+// upsampled with the same 9-3-3-1 filter as libwebp's fancy upsampler.
+// The Y plane is shared with m.
+func upsample420(m *image.YCbCr) *image.YCbCr {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	cw, ch := (w+1)/2, (h+1)/2
+	dst := &image.YCbCr{
+		Y:              m.Y,
+		Cb:             make([]uint8, w*h),
+		Cr:             make([]uint8, w*h),
+		YStride:        m.YStride,
+		CStride:        w,
+		SubsampleRatio: image.YCbCrSubsampleRatio444,
+		Rect:           m.Rect,
+	}
+	for y := 0; y < h; y++ {
+		cy, ny := y/2, y/2-1 //nearest and the other vertical chroma row
+		if y&1 == 1 {
+			ny = cy + 1
+		}
+		if ny < 0 || ny >= ch {
+			ny = cy
+		}
+		for x := 0; x < w; x++ {
+			cx, nx := x/2, x/2-1
+			if x&1 == 1 {
+				nx = cx + 1
+			}
+			if nx < 0 || nx >= cw {
+				nx = cx
+			}
+			a, b := cy*m.CStride+cx, cy*m.CStride+nx
+			c, d := ny*m.CStride+cx, ny*m.CStride+nx
+			i := y*dst.CStride + x
+			dst.Cb[i] = uint8((9*uint32(m.Cb[a]) + 3*uint32(m.Cb[b]) + 3*uint32(m.Cb[c]) + uint32(m.Cb[d]) + 8) >> 4)
+			dst.Cr[i] = uint8((9*uint32(m.Cr[a]) + 3*uint32(m.Cr[b]) + 3*uint32(m.Cr[c]) + uint32(m.Cr[d]) + 8) >> 4)
+		}
+	}
+	return dst
+}
+
+// toJFIF convert BT.601 studio range samples to JFIF full range in place
+func toJFIF(m *image.YCbCr) {
+	for i, y := range m.Y {
+		m.Y[i] = colorx.BT601ToGray(y)
+	}
+	for i := range m.Cb {
+		m.Cb[i] = colorx.BT601ChromaToFull(m.Cb[i])
+		m.Cr[i] = colorx.BT601ChromaToFull(m.Cr[i])
+	}
+}